@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
 	"os/signal"
@@ -25,6 +26,11 @@ func main() {
 	// Создаем менеджер экспорта
 	exportManager := export.NewManager(cfg)
 
+	// Проверка файлов запуска по манифесту: testops-export verify <манифест>
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(exportManager, os.Args[2:]))
+	}
+
 	// Создаем веб-сервер
 	server := web.NewServer(exportManager)
 
@@ -75,6 +81,17 @@ func main() {
 			log.Fatalf("Ошибка добавления cron задачи: %v", err)
 		}
 
+		// Добавляем периодическую проверку целостности хранилища
+		if cfg.ScrubSchedule != "off" {
+			_, err = c.AddFunc(cfg.ScrubSchedule, func() {
+				log.Printf("⏰ Запуск проверки целостности по расписанию (%s)...", cfg.ScrubSchedule)
+				exportManager.Scrub()
+			})
+			if err != nil {
+				log.Printf("Ошибка добавления задачи проверки целостности: %v", err)
+			}
+		}
+
 		// Запускаем планировщик
 		c.Start()
 		log.Printf("📅 Планировщик запущен. Автоматический экспорт будет выполняться по расписанию: %s", cfg.CronSchedule)
@@ -105,3 +122,29 @@ func main() {
 		log.Println("✅ Сервис остановлен")
 	})
 }
+
+// runVerify проверяет файлы по манифестам и печатает отчеты в JSON.
+// Возвращает код выхода: 0 — все файлы совпадают, 1 — найдены расхождения или ошибки.
+func runVerify(manager *export.Manager, manifests []string) int {
+	if len(manifests) == 0 {
+		log.Println("Использование: testops-export verify <testops_manifest_...json> [...]")
+		return 2
+	}
+
+	code := 0
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	for _, name := range manifests {
+		report, err := manager.VerifyManifest(name)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			code = 1
+			continue
+		}
+		enc.Encode(report)
+		if !report.OK {
+			code = 1
+		}
+	}
+	return code
+}
@@ -176,43 +176,41 @@ func createExportRequest(projectID int64, treeID int, groupID int) *models.Expor
 			Inverted:         false,
 			Deleted:          false,
 		},
-		Mapping: []struct {
-			Field          string `json:"field"`
-			Name           string `json:"name"`
-			ItemsSeparator string `json:"itemsSeparator,omitempty"`
-			IntegrationID  int    `json:"integrationId,omitempty"`
-			RoleID         int    `json:"roleId,omitempty"`
-			CustomFieldID  int    `json:"customFieldId,omitempty"`
-		}{
-			{Field: "allure_id", Name: "allure_id"},
-			{Field: "name", Name: "name"},
-			{Field: "full_name", Name: "full_name"},
-			{Field: "automated", Name: "automated"},
-			{Field: "description", Name: "description"},
-			{Field: "precondition", Name: "precondition"},
-			{Field: "expected_result", Name: "expected_result"},
-			{Field: "status", Name: "status"},
-			{Field: "scenario", Name: "scenario"},
-			{Field: "tag", Name: "tag", ItemsSeparator: ","},
-			{Field: "link", Name: "link"},
-			{Field: "example", Name: "example"},
-			{Field: "parameter", Name: "parameter", ItemsSeparator: ","},
-			{Field: "issue_integration", Name: "Gitlab", IntegrationID: 2, ItemsSeparator: ","},
-			{Field: "issue_integration", Name: "Интеграция с WB Youtrack", IntegrationID: 1, ItemsSeparator: ","},
-			{Field: "role", Name: "Lead", RoleID: -2, ItemsSeparator: ","},
-			{Field: "role", Name: "Owner", RoleID: -1, ItemsSeparator: ","},
-			{Field: "role", Name: "AutoQA", RoleID: 2, ItemsSeparator: ","},
-			{Field: "role", Name: "Author", RoleID: 3, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Suite", CustomFieldID: -5, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Component", CustomFieldID: -4, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Story", CustomFieldID: -3, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Feature", CustomFieldID: -2, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Epic", CustomFieldID: -1, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Sub-Element", CustomFieldID: 8, ItemsSeparator: ","},
-			{Field: "custom_field", Name: "Sub-Suite", CustomFieldID: 9, ItemsSeparator: ","},
-		},
+		Mapping:         ExportMapping(),
 		ColumnSeparator: ";",
 		IncludeHeaders:  true,
 		Name:            "report.csv",
 	}
 }
+
+// ExportMapping возвращает колонки, запрашиваемые в каждом экспорте
+func ExportMapping() []models.ExportMapping {
+	return []models.ExportMapping{
+		{Field: "allure_id", Name: "allure_id"},
+		{Field: "name", Name: "name"},
+		{Field: "full_name", Name: "full_name"},
+		{Field: "automated", Name: "automated"},
+		{Field: "description", Name: "description"},
+		{Field: "precondition", Name: "precondition"},
+		{Field: "expected_result", Name: "expected_result"},
+		{Field: "status", Name: "status"},
+		{Field: "scenario", Name: "scenario"},
+		{Field: "tag", Name: "tag", ItemsSeparator: ","},
+		{Field: "link", Name: "link"},
+		{Field: "example", Name: "example"},
+		{Field: "parameter", Name: "parameter", ItemsSeparator: ","},
+		{Field: "issue_integration", Name: "Gitlab", IntegrationID: 2, ItemsSeparator: ","},
+		{Field: "issue_integration", Name: "Интеграция с WB Youtrack", IntegrationID: 1, ItemsSeparator: ","},
+		{Field: "role", Name: "Lead", RoleID: -2, ItemsSeparator: ","},
+		{Field: "role", Name: "Owner", RoleID: -1, ItemsSeparator: ","},
+		{Field: "role", Name: "AutoQA", RoleID: 2, ItemsSeparator: ","},
+		{Field: "role", Name: "Author", RoleID: 3, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Suite", CustomFieldID: -5, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Component", CustomFieldID: -4, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Story", CustomFieldID: -3, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Feature", CustomFieldID: -2, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Epic", CustomFieldID: -1, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Sub-Element", CustomFieldID: 8, ItemsSeparator: ","},
+		{Field: "custom_field", Name: "Sub-Suite", CustomFieldID: 9, ItemsSeparator: ","},
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"testops-export/pkg/models"
@@ -11,6 +15,9 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// DefaultFilenameTemplate — шаблон имени файла экспорта по умолчанию
+const DefaultFilenameTemplate = "testops_export_{project}_{group}_{date}_{time}"
+
 // Config представляет конфигурацию приложения
 type Config struct {
 	BaseURL      string
@@ -21,20 +28,106 @@ type Config struct {
 	MaxRetries   int
 	RetryDelay   time.Duration
 	CronSchedule string // Добавляем настройку расписания
+	Compression  string // Сжатие сохраняемых экспортов: "", "gzip" или "zstd"
+	Bundle       string // ZIP архив запуска: "" (выключен), "project" или "all"
+	// Шаблон имени файла экспорта (без расширения), см. EXPORT_FILENAME_TEMPLATE
+	FilenameTemplate string
+	// Квоты хранилища в байтах (0 — без ограничения): общая и по умолчанию для каждого проекта
+	StorageQuota int64
+	ProjectQuota int64
+	// Проверка целостности хранилища: расписание cron (пусто — выключена) и карантин поврежденных файлов
+	ScrubSchedule   string
+	ScrubQuarantine bool
+	// Последний экспорт группы старше StaleAfter считается устаревшим (0 — не отмечать)
+	StaleAfter time.Duration
+
+	// Аутентификация веб-интерфейса и API
+	AuthMethods    []string          // Способы входа: token, basic, oidc; пусто — без аутентификации
+	AuthTokens     map[string]string // Статические токены API: токен → имя клиента
+	AuthBasicUsers map[string]string // Пользователи HTTP Basic: имя → пароль или bcrypt хеш
+	SessionSecret  string            // Ключ подписи cookie сессии, пусто — случайный при запуске
+	SessionTTL     time.Duration
+
+	// OIDC вход для пользователей (Keycloak, Dex и др.)
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string // Адрес /auth/callback этого сервиса, как его видит браузер
+	OIDCScopes       []string
+	OIDCGroupsClaim  string // Claim ID токена со списком групп пользователя
+
+	// Права пользователей на проекты (ACCESS_CONFIG), пусто — всем вошедшим доступно все
+	AccessRules []models.AccessRule
+
+	// Журнал аудита действий пользователей (JSON Lines), пусто — только в лог приложения
+	AuditLogPath string
+	// Обратные прокси, от которых принимается X-Forwarded-For (адреса или подсети)
+	TrustedProxies []*net.IPNet
 
 	// S3 конфигурация
 	S3Enabled   bool
 	S3Bucket    string
 	S3Endpoint  string
-	S3AccessKey string
+	S3AccessKey string // Необязательно: без ключей используется стандартная цепочка AWS (IRSA, профиль, роль инстанса)
 	S3SecretKey string
 	S3Region    string
+
+	// Скачивание по временным ссылкам S3 вместо проксирования через под
+	S3PresignDownloads bool
+	S3PresignExpiry    time.Duration
+	S3PublicEndpoint   string // Адрес S3, доступный из браузера, если отличается от S3_ENDPOINT
+
+	// Раскладка ключей S3
+	S3KeyPrefix   string // Общий префикс ключей, например "prod/"
+	S3KeyTemplate string // Шаблон ключа с плейсхолдерами {project}, {group}, {group_id}, {run_id}, {yyyy}, {mm}, {dd}, {filename}
+
+	// Учетные данные AWS без статических ключей
+	S3Profile               string // Профиль из ~/.aws/config
+	S3AssumeRoleARN         string // Роль, принимаемая поверх базовых учетных данных
+	S3AssumeRoleSessionName string
+	S3AssumeRoleExternalID  string
+
+	// Шифрование, класс хранения и теги объектов S3
+	S3SSE          string // "" — без SSE, "AES256" — ключи S3, "aws:kms" — ключ KMS
+	S3KMSKeyID     string // ID/ARN ключа KMS для S3_SSE=aws:kms, пусто — ключ aws/s3 по умолчанию
+	S3StorageClass string // Например STANDARD_IA, ONEZONE_IA, GLACIER_IR; пусто — класс бакета
+	S3TagObjects   bool   // Добавлять теги project-id, group-id, group к объектам
+	S3ExtraTags    string // Дополнительные теги вида "env=prod,team=qa"
+
+	// WebDAV конфигурация (например, Nextcloud)
+	WebDAVEnabled  bool
+	WebDAVURL      string
+	WebDAVUsername string
+	WebDAVPassword string
+
+	// Google Cloud Storage конфигурация
+	GCSEnabled         bool
+	GCSBucket          string
+	GCSCredentialsFile string // JSON ключ сервисного аккаунта, пусто — Application Default Credentials
+	GCSEndpoint        string // Для эмулятора (fake-gcs-server)
+
+	// Azure Blob Storage конфигурация
+	AzureEnabled          bool
+	AzureContainer        string
+	AzureAccountName      string
+	AzureAccountKey       string
+	AzureSASToken         string
+	AzureConnectionString string // Для Azurite и подключения по строке
+	AzureEndpoint         string
 }
 
 type ProjectsFile struct {
 	Projects []models.ProjectConfig `json:"projects"`
 }
 
+// AccessFile описывает файл прав доступа ACCESS_CONFIG
+type AccessFile struct {
+	Rules []models.AccessRule `json:"rules"`
+}
+
+// accessActions — действия, на которые выдаются права
+var accessActions = map[string]bool{"view": true, "download": true, "trigger": true, "delete": true, "admin": true}
+
 // Load загружает конфигурацию из переменных окружения
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
@@ -55,14 +148,30 @@ func Load() (*Config, error) {
 	}
 
 	config := &Config{
-		BaseURL:      getEnv("TESTOPS_BASE_URL", "https://your-testops.ru"),
-		Token:        getEnv("TESTOPS_TOKEN", ""),
-		ExportPath:   getEnv("EXPORT_PATH", "./exports"),
-		WebPort:      getEnv("WEB_PORT", "9090"),
-		MaxRetries:   3,
-		RetryDelay:   15 * time.Second,
-		CronSchedule: getEnv("CRON_SCHEDULE", "0 7 * * *"), // По умолчанию 7:00 UTC
-		Projects:     projectsFile.Projects,
+		BaseURL:          getEnv("TESTOPS_BASE_URL", "https://your-testops.ru"),
+		Token:            getEnv("TESTOPS_TOKEN", ""),
+		ExportPath:       getEnv("EXPORT_PATH", "./exports"),
+		WebPort:          getEnv("WEB_PORT", "9090"),
+		MaxRetries:       3,
+		RetryDelay:       15 * time.Second,
+		CronSchedule:     getEnv("CRON_SCHEDULE", "0 7 * * *"), // По умолчанию 7:00 UTC
+		Compression:      getEnv("EXPORT_COMPRESSION", ""),
+		Bundle:           getEnv("EXPORT_BUNDLE", ""),
+		FilenameTemplate: getEnv("EXPORT_FILENAME_TEMPLATE", DefaultFilenameTemplate),
+		ScrubSchedule:    getEnv("SCRUB_SCHEDULE", "0 3 * * 0"), // По умолчанию по воскресеньям в 3:00 UTC
+		ScrubQuarantine:  getEnvBool("SCRUB_QUARANTINE", true),
+		StaleAfter:       getEnvDuration("STALE_AFTER", 48*time.Hour),
+		// Аутентификация
+		AuthMethods:      splitList(getEnv("AUTH_METHODS", "")),
+		SessionSecret:    getEnv("SESSION_SECRET", ""),
+		SessionTTL:       getEnvDuration("SESSION_TTL", 12*time.Hour),
+		OIDCIssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       splitList(getEnv("OIDC_SCOPES", "openid,profile,email")),
+		OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		Projects:         projectsFile.Projects,
 		// S3 конфигурация
 		S3Enabled:   getEnvBool("S3_ENABLED", false),
 		S3Bucket:    getEnv("S3_BUCKET", ""),
@@ -70,6 +179,42 @@ func Load() (*Config, error) {
 		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
 		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
 		S3Region:    getEnv("S3_REGION", "us-east-1"),
+		// Временные ссылки S3
+		S3PresignDownloads: getEnvBool("S3_PRESIGN_DOWNLOADS", false),
+		S3PresignExpiry:    getEnvDuration("S3_PRESIGN_EXPIRY", 15*time.Minute),
+		S3PublicEndpoint:   getEnv("S3_PUBLIC_ENDPOINT", ""),
+		// Раскладка ключей S3
+		S3KeyPrefix:   getEnv("S3_KEY_PREFIX", ""),
+		S3KeyTemplate: getEnv("S3_KEY_TEMPLATE", "exports/{yyyy}-{mm}-{dd}/{filename}"),
+		// Учетные данные AWS
+		S3Profile:               getEnv("S3_PROFILE", ""),
+		S3AssumeRoleARN:         getEnv("S3_ASSUME_ROLE_ARN", ""),
+		S3AssumeRoleSessionName: getEnv("S3_ASSUME_ROLE_SESSION_NAME", "testops-export"),
+		S3AssumeRoleExternalID:  getEnv("S3_ASSUME_ROLE_EXTERNAL_ID", ""),
+		// Шифрование, класс хранения и теги S3
+		S3SSE:          getEnv("S3_SSE", ""),
+		S3KMSKeyID:     getEnv("S3_SSE_KMS_KEY_ID", ""),
+		S3StorageClass: getEnv("S3_STORAGE_CLASS", ""),
+		S3TagObjects:   getEnvBool("S3_TAG_OBJECTS", false),
+		S3ExtraTags:    getEnv("S3_EXTRA_TAGS", ""),
+		// WebDAV конфигурация
+		WebDAVEnabled:  getEnvBool("WEBDAV_ENABLED", false),
+		WebDAVURL:      getEnv("WEBDAV_URL", ""),
+		WebDAVUsername: getEnv("WEBDAV_USERNAME", ""),
+		WebDAVPassword: getEnv("WEBDAV_PASSWORD", ""),
+		// GCS конфигурация
+		GCSEnabled:         getEnvBool("GCS_ENABLED", false),
+		GCSBucket:          getEnv("GCS_BUCKET", ""),
+		GCSCredentialsFile: getEnv("GCS_CREDENTIALS_FILE", ""),
+		GCSEndpoint:        getEnv("GCS_ENDPOINT", ""),
+		// Azure Blob конфигурация
+		AzureEnabled:          getEnvBool("AZURE_ENABLED", false),
+		AzureContainer:        getEnv("AZURE_CONTAINER", ""),
+		AzureAccountName:      getEnv("AZURE_ACCOUNT_NAME", ""),
+		AzureAccountKey:       getEnv("AZURE_ACCOUNT_KEY", ""),
+		AzureSASToken:         getEnv("AZURE_SAS_TOKEN", ""),
+		AzureConnectionString: getEnv("AZURE_CONNECTION_STRING", ""),
+		AzureEndpoint:         getEnv("AZURE_ENDPOINT", ""),
 	}
 
 	if config.Token == "" {
@@ -81,11 +226,120 @@ func Load() (*Config, error) {
 		if config.S3Bucket == "" {
 			return nil, fmt.Errorf("S3_BUCKET должен быть установлен когда S3_ENABLED=true")
 		}
-		if config.S3AccessKey == "" {
-			return nil, fmt.Errorf("S3_ACCESS_KEY должен быть установлен когда S3_ENABLED=true")
+		if !strings.HasSuffix(config.S3KeyTemplate, "{filename}") {
+			return nil, fmt.Errorf("S3_KEY_TEMPLATE должен заканчиваться на {filename}")
+		}
+		// Подпись SigV4 действительна не более 7 дней
+		if config.S3PresignExpiry <= 0 || config.S3PresignExpiry > 7*24*time.Hour {
+			return nil, fmt.Errorf("S3_PRESIGN_EXPIRY должен быть от 1s до 168h, получено: %s", config.S3PresignExpiry)
+		}
+		// Статические ключи необязательны, но задаются только парой
+		if (config.S3AccessKey == "") != (config.S3SecretKey == "") {
+			return nil, fmt.Errorf("S3_ACCESS_KEY и S3_SECRET_KEY должны быть установлены вместе")
+		}
+		if config.S3SSE != "" && config.S3SSE != "AES256" && config.S3SSE != "aws:kms" {
+			return nil, fmt.Errorf("S3_SSE должен быть AES256 или aws:kms, получено: %s", config.S3SSE)
+		}
+		if config.S3KMSKeyID != "" && config.S3SSE != "aws:kms" {
+			return nil, fmt.Errorf("S3_SSE_KMS_KEY_ID можно указать только вместе с S3_SSE=aws:kms")
+		}
+	}
+
+	if config.Compression != "" && config.Compression != "gzip" && config.Compression != "zstd" {
+		return nil, fmt.Errorf("EXPORT_COMPRESSION должен быть gzip или zstd, получено: %s", config.Compression)
+	}
+
+	if config.Bundle != "" && config.Bundle != "project" && config.Bundle != "all" {
+		return nil, fmt.Errorf("EXPORT_BUNDLE должен быть project или all, получено: %s", config.Bundle)
+	}
+
+	if err := validateFilenameTemplate(config.FilenameTemplate); err != nil {
+		return nil, err
+	}
+
+	if config.StaleAfter < 0 {
+		return nil, fmt.Errorf("STALE_AFTER не может быть отрицательным, получено: %s", config.StaleAfter)
+	}
+
+	if config.StorageQuota, err = ParseSize(getEnv("STORAGE_QUOTA", "")); err != nil {
+		return nil, fmt.Errorf("неверное значение STORAGE_QUOTA: %v", err)
+	}
+	if config.ProjectQuota, err = ParseSize(getEnv("STORAGE_PROJECT_QUOTA", "")); err != nil {
+		return nil, fmt.Errorf("неверное значение STORAGE_PROJECT_QUOTA: %v", err)
+	}
+	for i, p := range config.Projects {
+		if config.Projects[i].QuotaBytes, err = ParseSize(p.Quota); err != nil {
+			return nil, fmt.Errorf("неверная квота проекта %d: %v", p.ProjectID, err)
+		}
+	}
+
+	config.AuditLogPath = getEnv("AUDIT_LOG", filepath.Join(config.ExportPath, "audit.log"))
+	if config.AuditLogPath == "off" {
+		config.AuditLogPath = ""
+	}
+
+	if config.TrustedProxies, err = parseNetworks(getEnv("TRUSTED_PROXIES", "")); err != nil {
+		return nil, fmt.Errorf("неверное значение TRUSTED_PROXIES: %v", err)
+	}
+
+	if config.AuthTokens, err = parseCredentials(getEnv("AUTH_TOKENS", "")); err != nil {
+		return nil, fmt.Errorf("неверное значение AUTH_TOKENS: %v", err)
+	}
+	// В AUTH_TOKENS пары записаны как имя:токен, а искать нужно по токену
+	tokens := make(map[string]string, len(config.AuthTokens))
+	for name, token := range config.AuthTokens {
+		if _, dup := tokens[token]; dup {
+			return nil, fmt.Errorf("неверное значение AUTH_TOKENS: у клиентов %s и %s одинаковый токен", tokens[token], name)
+		}
+		tokens[token] = name
+	}
+	config.AuthTokens = tokens
+	if config.AuthBasicUsers, err = parseCredentials(getEnv("AUTH_BASIC_USERS", "")); err != nil {
+		return nil, fmt.Errorf("неверное значение AUTH_BASIC_USERS: %v", err)
+	}
+	if err := config.validateAuth(); err != nil {
+		return nil, err
+	}
+	if accessPath := getEnv("ACCESS_CONFIG", ""); accessPath != "" {
+		if config.AccessRules, err = loadAccessRules(accessPath); err != nil {
+			return nil, err
+		}
+		if len(config.AuthMethods) == 0 {
+			return nil, fmt.Errorf("ACCESS_CONFIG требует включенной аутентификации (AUTH_METHODS)")
 		}
-		if config.S3SecretKey == "" {
-			return nil, fmt.Errorf("S3_SECRET_KEY должен быть установлен когда S3_ENABLED=true")
+	}
+
+	// Одновременно может быть включено только одно удаленное хранилище
+	enabled := 0
+	for _, on := range []bool{config.S3Enabled, config.WebDAVEnabled, config.GCSEnabled, config.AzureEnabled} {
+		if on {
+			enabled++
+		}
+	}
+	if enabled > 1 {
+		return nil, fmt.Errorf("одновременно может быть включено только одно из S3_ENABLED, WEBDAV_ENABLED, GCS_ENABLED, AZURE_ENABLED")
+	}
+
+	// Проверяем WebDAV конфигурацию если она включена
+	if config.WebDAVEnabled && config.WebDAVURL == "" {
+		return nil, fmt.Errorf("WEBDAV_URL должен быть установлен когда WEBDAV_ENABLED=true")
+	}
+
+	// Проверяем GCS конфигурацию если она включена
+	if config.GCSEnabled && config.GCSBucket == "" {
+		return nil, fmt.Errorf("GCS_BUCKET должен быть установлен когда GCS_ENABLED=true")
+	}
+
+	// Проверяем Azure конфигурацию если она включена
+	if config.AzureEnabled {
+		if config.AzureContainer == "" {
+			return nil, fmt.Errorf("AZURE_CONTAINER должен быть установлен когда AZURE_ENABLED=true")
+		}
+		if config.AzureConnectionString == "" && config.AzureAccountName == "" && config.AzureEndpoint == "" {
+			return nil, fmt.Errorf("AZURE_CONNECTION_STRING или AZURE_ACCOUNT_NAME должны быть установлены когда AZURE_ENABLED=true")
+		}
+		if config.AzureConnectionString == "" && config.AzureAccountKey == "" && config.AzureSASToken == "" {
+			return nil, fmt.Errorf("AZURE_ACCOUNT_KEY или AZURE_SAS_TOKEN должны быть установлены когда AZURE_ENABLED=true")
 		}
 	}
 
@@ -124,3 +378,195 @@ func getEnvBool(key string, defaultValue bool) bool {
 	// fmt.Printf("🔍 %s: %t (из окружения)\n", key, result)
 	return result
 }
+
+// validateAuth проверяет, что для включенных способов входа заданы все параметры
+func (c *Config) validateAuth() error {
+	for _, method := range c.AuthMethods {
+		switch method {
+		case "token":
+			if len(c.AuthTokens) == 0 {
+				return fmt.Errorf("AUTH_TOKENS должен быть установлен когда AUTH_METHODS содержит token")
+			}
+		case "basic":
+			if len(c.AuthBasicUsers) == 0 {
+				return fmt.Errorf("AUTH_BASIC_USERS должен быть установлен когда AUTH_METHODS содержит basic")
+			}
+		case "oidc":
+			if c.OIDCIssuerURL == "" || c.OIDCClientID == "" || c.OIDCRedirectURL == "" {
+				return fmt.Errorf("OIDC_ISSUER_URL, OIDC_CLIENT_ID и OIDC_REDIRECT_URL должны быть установлены когда AUTH_METHODS содержит oidc")
+			}
+		default:
+			return fmt.Errorf("AUTH_METHODS может содержать token, basic, oidc, получено: %s", method)
+		}
+	}
+	if c.SessionTTL <= 0 {
+		return fmt.Errorf("SESSION_TTL должен быть больше нуля, получено: %s", c.SessionTTL)
+	}
+	return nil
+}
+
+// loadAccessRules читает и проверяет файл прав доступа
+func loadAccessRules(path string) ([]models.AccessRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка чтения файла прав доступа: %v", err)
+	}
+	var accessFile AccessFile
+	if err := json.Unmarshal(data, &accessFile); err != nil {
+		return nil, fmt.Errorf("Ошибка парсинга JSON прав доступа: %v", err)
+	}
+	for i, rule := range accessFile.Rules {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return nil, fmt.Errorf("правило доступа %d: укажите users или groups", i+1)
+		}
+		if len(rule.Projects) == 0 && !rule.AllProjects {
+			return nil, fmt.Errorf("правило доступа %d: укажите projects или all_projects", i+1)
+		}
+		if len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("правило доступа %d: укажите actions", i+1)
+		}
+		for _, action := range rule.Actions {
+			if !accessActions[action] {
+				return nil, fmt.Errorf("правило доступа %d: неизвестное действие %s (допустимо view, download, trigger, delete, admin)", i+1, action)
+			}
+		}
+	}
+	return accessFile.Rules, nil
+}
+
+// AuthEnabled сообщает, включен ли способ входа method (token, basic, oidc)
+func (c *Config) AuthEnabled(method string) bool {
+	for _, m := range c.AuthMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrustedProxy проверяет, что адрес относится к доверенному обратному прокси (TRUSTED_PROXIES)
+func (c *Config) IsTrustedProxy(ip net.IP) bool {
+	for _, network := range c.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetworks разбирает список адресов и подсетей через запятую: "10.0.0.0/8,192.168.1.10"
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range splitList(value) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("ожидается адрес или подсеть, получено: %s", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("ожидается адрес или подсеть, получено: %s", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCredentials разбирает пары вида "имя:секрет,имя2:секрет2"
+func parseCredentials(value string) (map[string]string, error) {
+	creds := make(map[string]string)
+	for _, pair := range splitList(value) {
+		name, secret, ok := strings.Cut(pair, ":")
+		name, secret = strings.TrimSpace(name), strings.TrimSpace(secret)
+		if !ok || name == "" || secret == "" {
+			return nil, fmt.Errorf("ожидается имя:секрет, получено: %s", name)
+		}
+		if _, dup := creds[name]; dup {
+			return nil, fmt.Errorf("имя %s указано дважды", name)
+		}
+		creds[name] = secret
+	}
+	return creds, nil
+}
+
+// validateFilenameTemplate проверяет, что имена файлов по шаблону не пересекаются
+// между группами и запусками и не содержат путей
+func validateFilenameTemplate(tmpl string) error {
+	if strings.ContainsAny(tmpl, "/\\") || strings.Contains(tmpl, "..") {
+		return fmt.Errorf("EXPORT_FILENAME_TEMPLATE не должен содержать /, \\ и ..: %s", tmpl)
+	}
+	if !strings.Contains(tmpl, "{group}") && !strings.Contains(tmpl, "{group_id}") {
+		return fmt.Errorf("EXPORT_FILENAME_TEMPLATE должен содержать {group} или {group_id}: %s", tmpl)
+	}
+	if !strings.Contains(tmpl, "{time}") && !strings.Contains(tmpl, "{run_id}") {
+		return fmt.Errorf("EXPORT_FILENAME_TEMPLATE должен содержать {time} или {run_id}: %s", tmpl)
+	}
+	return nil
+}
+
+// ParseSize разбирает размер вида "500Mi", "1Gi", "200MB" или число байт.
+// Суффиксы Ki/Mi/Gi/Ti — двоичные, K/M/G/T и KB/MB/GB/TB — десятичные. Пустая строка — 0.
+func ParseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+		{"B", 1},
+	}
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("неверный размер: %s", value)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// getEnvDuration получает длительность из переменной окружения (например, "15m", "1h")
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Printf("⚠️  Неверное значение %s=%s, используется %s\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return result
+}
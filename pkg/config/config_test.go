@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net"
+	"testing"
+)
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{DefaultFilenameTemplate, false},
+		{"{project}_{group_id}_{run_id}", false},
+		{"export_{group}_{date}_{time}", false},
+		{"{project}/{group}_{time}", true},
+		{"{project}\\{group}_{time}", true},
+		{"..{group}_{time}", true},
+		{"{project}_{date}_{time}", true},
+		{"{project}_{group}_{date}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			err := validateFilenameTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFilenameTemplate(%q) = %v, ошибка ожидалась: %v", tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"1024", 1024, false},
+		{"500Mi", 500 << 20, false},
+		{"1Gi", 1 << 30, false},
+		{"2GiB", 2 << 30, false},
+		{"1.5Ki", 1536, false},
+		{"200MB", 200e6, false},
+		{"3T", 3e12, false},
+		{" 10 Ki ", 10 << 10, false},
+		{"100B", 100, false},
+		{"-1Gi", 0, true},
+		{"Gi", 0, true},
+		{"много", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) ошибка %v, ошибка ожидалась: %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, ожидалось %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	tests := []struct {
+		value   string
+		trusted []string
+		other   []string
+		wantErr bool
+	}{
+		{value: "", other: []string{"10.0.0.1"}},
+		{value: "10.0.0.0/8", trusted: []string{"10.1.2.3"}, other: []string{"192.168.0.1"}},
+		{value: "192.168.1.10, 172.16.0.0/12", trusted: []string{"192.168.1.10", "172.20.0.1"}, other: []string{"192.168.1.11"}},
+		{value: "::1", trusted: []string{"::1"}, other: []string{"127.0.0.1"}},
+		{value: "10.0.0.0/33", wantErr: true},
+		{value: "proxy.local", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			networks, err := parseNetworks(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNetworks(%q) ошибка %v, ошибка ожидалась: %v", tt.value, err, tt.wantErr)
+			}
+			cfg := &Config{TrustedProxies: networks}
+			for _, ip := range tt.trusted {
+				if !cfg.IsTrustedProxy(net.ParseIP(ip)) {
+					t.Errorf("%s должен быть доверенным для %q", ip, tt.value)
+				}
+			}
+			for _, ip := range tt.other {
+				if cfg.IsTrustedProxy(net.ParseIP(ip)) {
+					t.Errorf("%s не должен быть доверенным для %q", ip, tt.value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"testops-export/pkg/models"
+)
+
+// Audit записывает действие пользователя в лог приложения и в журнал аудита (AUDIT_LOG)
+func (m *Manager) Audit(rec models.AuditRecord) {
+	user := rec.User
+	if user == "" {
+		user = "-"
+	}
+	addr := rec.RemoteAddr
+	if rec.ForwardedFor != "" {
+		addr += ", X-Forwarded-For " + rec.ForwardedFor
+	}
+	log.Printf("AUDIT %s: пользователь %s (%s), файл %s, проект %d, результат %s %s",
+		rec.Action, user, addr, rec.File, rec.ProjectID, rec.Result, rec.Error)
+
+	if m.config.AuditLogPath == "" {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(m.config.AuditLogPath), 0755); err != nil {
+		log.Printf("Ошибка записи журнала аудита: %v", err)
+		return
+	}
+	f, err := os.OpenFile(m.config.AuditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Ошибка записи журнала аудита: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Ошибка записи журнала аудита: %v", err)
+	}
+}
+
+// AuditLog возвращает последние limit записей журнала аудита, новые первыми
+func (m *Manager) AuditLog(limit int) ([]models.AuditRecord, error) {
+	records := []models.AuditRecord{}
+	if m.config.AuditLogPath == "" {
+		return records, nil
+	}
+
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	f, err := os.Open(m.config.AuditLogPath)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала аудита: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec models.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала аудита: %v", err)
+	}
+
+	// Новые записи первыми
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
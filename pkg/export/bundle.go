@@ -0,0 +1,267 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"testops-export/pkg/api"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// exportResult описывает успешно сохраненный экспорт группы
+type exportResult struct {
+	File      models.ManifestFile // Описание сохраненного файла для манифеста
+	CreatedAt time.Time
+	Data      []byte // Несжатое содержимое CSV (только при включенном EXPORT_BUNDLE)
+}
+
+// runResults собирает результаты групп одного запуска, в том числе из параллельных горутин
+type runResults struct {
+	mu      sync.Mutex
+	results []*exportResult
+}
+
+func (r *runResults) add(res *exportResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, res)
+}
+
+func (r *runResults) list() []*exportResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.results
+}
+
+func (r *runResults) filenames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.results))
+	for _, res := range r.results {
+		names = append(names, res.File.Name)
+	}
+	return names
+}
+
+// finishRun сохраняет ZIP архивы запуска (если включены) и манифест запуска и отмечает запуск завершенным
+func (m *Manager) finishRun(runID string, results []*exportResult) {
+	defer m.runs.finish(runID)
+
+	if len(results) == 0 {
+		return
+	}
+
+	files := make([]models.ManifestFile, 0, len(results))
+	for _, res := range results {
+		files = append(files, res.File)
+	}
+	files = append(files, m.saveBundles(runID, results)...)
+	m.saveRunManifest(runID, files)
+}
+
+// saveBundles сохраняет ZIP архивы запуска (EXPORT_BUNDLE) и возвращает их описание для манифеста
+func (m *Manager) saveBundles(runID string, results []*exportResult) []models.ManifestFile {
+	if m.config.Bundle == "" {
+		return nil
+	}
+
+	// Группируем результаты: один архив на проект или один на весь запуск
+	bundles := make(map[int64][]*exportResult)
+	for _, res := range results {
+		key := int64(0)
+		if m.config.Bundle == "project" {
+			key = res.File.ProjectID
+		}
+		bundles[key] = append(bundles[key], res)
+	}
+
+	var saved []models.ManifestFile
+	for projectID, items := range bundles {
+		filename := storage.BundleFilename(projectID, runID)
+		file, err := m.saveBundle(filename, projectID, runID, items)
+		if err != nil {
+			log.Printf("❌ Ошибка сохранения архива %s: %v", filename, err)
+			continue
+		}
+		saved = append(saved, file)
+		log.Printf("[OK]    Архив запуска %s, файлов: %d", filename, len(items))
+	}
+	return saved
+}
+
+// saveBundle собирает ZIP архив из результатов запуска и сохраняет его в хранилище
+func (m *Manager) saveBundle(filename string, projectID int64, runID string, items []*exportResult) (models.ManifestFile, error) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].File.Name < items[j].File.Name
+	})
+
+	now := time.Now()
+	manifest := models.RunManifest{
+		RunID:     runID,
+		CreatedAt: now.UTC(),
+		ProjectID: projectID,
+		Mapping:   api.ExportMapping(),
+	}
+	entries := make([]zipEntry, 0, len(items))
+	for _, res := range items {
+		// В архиве лежат несжатые CSV, поэтому размер и сумма пересчитываются
+		file := res.File
+		file.Name = storage.UncompressedName(file.Name)
+		file.Size = int64(len(res.Data))
+		file.SHA256 = sha256Hex(res.Data)
+		file.Compression = storage.CompressionNone
+		entries = append(entries, zipEntry{name: file.Name, data: res.Data})
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	data, err := writeZip(entries, manifest)
+	if err != nil {
+		return models.ManifestFile{}, err
+	}
+
+	meta := models.ExportMetadata{
+		Filename:  filename,
+		ProjectID: projectID,
+		GroupName: storage.BundleGroupName,
+		RunID:     runID,
+		CreatedAt: now,
+	}
+	if err := m.storeFile(data, meta); err != nil {
+		return models.ManifestFile{}, err
+	}
+
+	file := describeFile(filename, data, nil)
+	file.ProjectID = projectID
+	file.GroupName = storage.BundleGroupName
+	return file, nil
+}
+
+// MaxZipFiles — сколько файлов можно выбрать для скачивания одним архивом за запрос
+const MaxZipFiles = 100
+
+// ZipSources проверяет, что выбранные для архива файлы есть в хранилище, и возвращает их без повторов
+func (m *Manager) ZipSources(filenames []string) ([]models.ExportFile, error) {
+	files, err := m.listAllFiles()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка файлов: %v", err)
+	}
+	known := make(map[string]models.ExportFile, len(files))
+	for _, f := range files {
+		known[f.Name] = f
+	}
+
+	sources := make([]models.ExportFile, 0, len(filenames))
+	seen := make(map[string]bool)
+	for _, filename := range filenames {
+		if seen[filename] {
+			continue
+		}
+		seen[filename] = true
+
+		info, ok := known[filename]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, filename)
+		}
+		sources = append(sources, info)
+	}
+	return sources, nil
+}
+
+// WriteZip пишет ZIP архив из файлов экспорта в w «на лету»: файлы читаются и распаковываются
+// по одному, чтобы в памяти не было всего архива. В конце добавляется manifest.json.
+// Ошибка возможна после начала записи — тогда архив получится оборванным.
+func (m *Manager) WriteZip(w io.Writer, files []models.ExportFile) error {
+	manifest := models.RunManifest{CreatedAt: time.Now().UTC()}
+	archive := newZipArchive(w, manifest.CreatedAt)
+	for _, info := range files {
+		data, err := m.DownloadDecompressedExportFile(info.Name)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла %s: %v", info.Name, err)
+		}
+
+		name := storage.UncompressedName(storage.SnapshotSourceName(info.Name))
+		var csvData []byte
+		if strings.HasSuffix(name, ".csv") {
+			csvData = data
+		}
+		file := describeFile(name, data, csvData)
+		file.ProjectID = info.ProjectID
+		file.GroupName = info.GroupName
+		if err := archive.add(file.Name, data); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	return archive.close(manifest)
+}
+
+// zipEntry описывает файл внутри ZIP архива
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+// writeZip записывает файлы и manifest.json в ZIP архив в памяти (для архивов запусков)
+func writeZip(entries []zipEntry, manifest models.RunManifest) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := newZipArchive(&buf, manifest.CreatedAt)
+	for _, e := range entries {
+		if err := archive.add(e.name, e.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.close(manifest); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// zipArchive последовательно записывает файлы в ZIP архив
+type zipArchive struct {
+	zw       *zip.Writer
+	modified time.Time
+}
+
+func newZipArchive(w io.Writer, modified time.Time) *zipArchive {
+	return &zipArchive{zw: zip.NewWriter(w), modified: modified}
+}
+
+// add добавляет файл в архив
+func (a *zipArchive) add(name string, data []byte) error {
+	w, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: a.modified,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка добавления %s в архив: %v", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("ошибка записи %s в архив: %v", name, err)
+	}
+	return nil
+}
+
+// close добавляет manifest.json и завершает архив
+func (a *zipArchive) close(manifest models.RunManifest) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка формирования manifest.json: %v", err)
+	}
+	if err := a.add("manifest.json", manifestData); err != nil {
+		return err
+	}
+	if err := a.zw.Close(); err != nil {
+		return fmt.Errorf("ошибка закрытия архива: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// exportFilename формирует имя файла экспорта по шаблону EXPORT_FILENAME_TEMPLATE.
+// Это единственное место, где строится имя: оно же сохраняется, логируется и возвращается вызывающим.
+func (m *Manager) exportFilename(projectID int64, group models.ExportGroupConfig, runID string, created time.Time) string {
+	tmpl := m.config.FilenameTemplate
+	if tmpl == "" {
+		tmpl = config.DefaultFilenameTemplate
+	}
+
+	name := strings.NewReplacer(
+		"{project}", strconv.FormatInt(projectID, 10),
+		"{group}", storage.SanitizeName(group.GroupName),
+		"{group_id}", strconv.Itoa(group.GroupID),
+		"{run_id}", runID,
+		"{date}", created.Format("2006-01-02"),
+		"{time}", created.Format("15-04-05"),
+	).Replace(tmpl)
+
+	return name + ".csv" + storage.CompressionExt(m.config.Compression)
+}
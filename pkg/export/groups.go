@@ -0,0 +1,90 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/models"
+)
+
+// GetGroups возвращает группы из конфигурации с последним экспортом и последним запуском каждой
+// (projectID 0 — группы всех проектов)
+func (m *Manager) GetGroups(projectID int64) ([]models.GroupOverview, error) {
+	files, err := m.listAllFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	runs := m.GetRuns()
+	var groups []models.GroupOverview
+	for _, t := range m.runTargets(projectID, nil) {
+		overview := models.GroupOverview{ProjectID: t.projectID, GroupID: t.group.GroupID, GroupName: t.group.GroupName}
+		// Файлы отсортированы от новых к старым — первый подходящий и есть последний экспорт
+		for _, f := range files {
+			if groupFile(f, t) {
+				last := f
+				overview.LastFile = &last
+				break
+			}
+		}
+		if overview.LastFile != nil {
+			age := now.Sub(overview.LastFile.ModifiedTime)
+			overview.Age = FormatAge(age)
+			overview.Stale = m.config.StaleAfter > 0 && age > m.config.StaleAfter
+		} else {
+			overview.Stale = m.config.StaleAfter > 0
+		}
+		overview.LastRunID, overview.LastRun = lastGroupRun(runs, t.projectID, t.group.GroupID)
+		groups = append(groups, overview)
+	}
+	return groups, nil
+}
+
+// LatestGroupFile возвращает последний успешный экспорт группы проекта. Группа задается
+// именем из конфигурации (без учета регистра) или ID
+func (m *Manager) LatestGroupFile(projectID int64, group string) (models.ExportFile, error) {
+	for _, t := range m.runTargets(projectID, nil) {
+		if !strings.EqualFold(t.group.GroupName, group) && strconv.Itoa(t.group.GroupID) != group {
+			continue
+		}
+		files, err := m.listAllFiles()
+		if err != nil {
+			return models.ExportFile{}, err
+		}
+		for _, f := range files {
+			if groupFile(f, t) {
+				return f, nil
+			}
+		}
+		return models.ExportFile{}, fmt.Errorf("%w: экспортов группы %s проекта %d еще нет", ErrFileNotFound, t.group.GroupName, projectID)
+	}
+	return models.ExportFile{}, fmt.Errorf("%w: группа %s не найдена в проекте %d", ErrFileNotFound, group, projectID)
+}
+
+// groupFile проверяет, что файл — экспорт группы. У файлов без метаданных группа известна только по имени
+func groupFile(f models.ExportFile, t runTarget) bool {
+	if f.Snapshot != "" || f.Bundle || f.Manifest || f.Quarantined || f.ProjectID != t.projectID {
+		return false
+	}
+	if f.GroupID != 0 {
+		return f.GroupID == t.group.GroupID
+	}
+	return strings.EqualFold(f.GroupName, t.group.GroupName)
+}
+
+// FormatAge возвращает длительность в понятном виде: «5 мин», «3 ч», «2 дн»
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "меньше минуты"
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d ч", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d дн", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// metadataTTL — сколько метаданные отдельного файла живут в кэше. Файлы метаданных меняют
+// и другие реплики (закрепление, карантин), поэтому кэш только ускоряет списки, а решения
+// об удалении принимаются по метаданным, заново прочитанным из хранилища.
+// Списки файлов сверяются с версией файла метаданных и от TTL не зависят
+const metadataTTL = time.Minute
+
+// metadataReaders — сколько файлов метаданных читается из хранилища одновременно
+const metadataReaders = 8
+
+// metadataIndex кэширует метаданные файлов, прочитанные из файлов <имя>.meta.json.
+// Отсутствие метаданных (файлы, сохраненные до появления индекса) тоже кэшируется — значением nil.
+type metadataIndex struct {
+	mu      sync.RWMutex
+	entries map[string]indexEntry
+}
+
+type indexEntry struct {
+	meta     *models.ExportMetadata
+	version  string // Версия файла метаданных из списка хранилища (пусто — неизвестна)
+	loadedAt time.Time
+}
+
+func newMetadataIndex() *metadataIndex {
+	return &metadataIndex{entries: make(map[string]indexEntry)}
+}
+
+// get возвращает метаданные из кэша, если они прочитаны не раньше metadataTTL назад
+func (idx *metadataIndex) get(filename string) (*models.ExportMetadata, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	entry, ok := idx.entries[filename]
+	if !ok || time.Since(entry.loadedAt) > metadataTTL {
+		return nil, false
+	}
+	return entry.meta, true
+}
+
+// cached возвращает метаданные из кэша, если они прочитаны из файла метаданных той же версии
+func (idx *metadataIndex) cached(filename, version string) (*models.ExportMetadata, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	entry, ok := idx.entries[filename]
+	if !ok || version == "" || entry.version != version {
+		return nil, false
+	}
+	return entry.meta, true
+}
+
+func (idx *metadataIndex) put(filename string, meta *models.ExportMetadata, version string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries[filename] = indexEntry{meta: meta, version: version, loadedAt: time.Now()}
+}
+
+func (idx *metadataIndex) remove(filename string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.entries, filename)
+}
+
+// retain удаляет из кэша файлы, которых больше нет в хранилище
+func (idx *metadataIndex) retain(files []models.ExportFile) {
+	existing := make(map[string]bool, len(files))
+	for _, f := range files {
+		existing[f.Name] = true
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for name := range idx.entries {
+		if !existing[name] {
+			delete(idx.entries, name)
+		}
+	}
+}
+
+// storeFile сохраняет файл вместе с метаданными, предварительно освобождая место по квоте. Метаданные записываются первыми,
+// чтобы файл никогда не был виден в списке без них.
+func (m *Manager) storeFile(data []byte, meta models.ExportMetadata) error {
+	meta.Size = int64(len(data))
+	meta.SHA256 = sha256Hex(data)
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	if err := m.ensureQuota(meta.ProjectID, meta.Size); err != nil {
+		return err
+	}
+
+	if err := m.saveMetadata(meta); err != nil {
+		return err
+	}
+	return m.storage.SaveFile(data, meta.Filename, meta)
+}
+
+// saveMetadata записывает файл метаданных и обновляет индекс
+func (m *Manager) saveMetadata(meta models.ExportMetadata) error {
+	sidecar, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка формирования метаданных %s: %v", meta.Filename, err)
+	}
+	if err := m.storage.SaveFile(sidecar, storage.MetaSidecarName(meta.Filename), meta); err != nil {
+		return fmt.Errorf("ошибка сохранения метаданных %s: %v", meta.Filename, err)
+	}
+	m.index.put(meta.Filename, &meta, "")
+	return nil
+}
+
+// lookupMetadata возвращает метаданные файла из кэша или из хранилища (nil, если их нет или
+// их не удалось прочитать). Для решений об удалении используется readMetadata
+func (m *Manager) lookupMetadata(filename string) *models.ExportMetadata {
+	if meta, ok := m.index.get(filename); ok {
+		return meta
+	}
+	meta, err := m.readMetadata(filename)
+	if err != nil {
+		log.Printf("Ошибка чтения метаданных %s: %v", filename, err)
+	}
+	return meta
+}
+
+// readMetadata читает метаданные файла из хранилища в обход кэша и обновляет кэш.
+// Отсутствие файла метаданных — не ошибка (nil, nil); ошибка чтения или разбора возвращается,
+// чтобы вызывающий не принял недоступные метаданные за их отсутствие
+func (m *Manager) readMetadata(filename string) (*models.ExportMetadata, error) {
+	return m.readMetadataVersion(filename, "")
+}
+
+// readMetadataVersion читает метаданные файла и запоминает в кэше версию файла метаданных из списка
+func (m *Manager) readMetadataVersion(filename, version string) (*models.ExportMetadata, error) {
+	data, err := m.storage.GetFile(storage.MetaSidecarName(filename))
+	if errors.Is(err, storage.ErrNotFound) {
+		m.index.put(filename, nil, "")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := &models.ExportMetadata{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("метаданные не разбираются: %v", err)
+	}
+	m.index.put(filename, meta, version)
+	return meta, nil
+}
+
+// updateMetadata меняет метаданные файла: читает их заново из хранилища, применяет update
+// и сохраняет, если update вернул true. Изменения выполняются по одному, чтобы одновременные
+// закрепление и карантин не затирали друг друга
+func (m *Manager) updateMetadata(f models.ExportFile, update func(meta *models.ExportMetadata) bool) (bool, error) {
+	m.metaMu.Lock()
+	defer m.metaMu.Unlock()
+
+	existing, err := m.readMetadata(f.Name)
+	if err != nil {
+		return false, fmt.Errorf("ошибка чтения метаданных %s: %v", f.Name, err)
+	}
+	meta := defaultMetadata(f)
+	if existing != nil {
+		meta = *existing
+	}
+	if !update(&meta) {
+		return false, nil
+	}
+	return true, m.saveMetadata(meta)
+}
+
+// metadataFor возвращает метаданные файла из списка. Для файлов без метаданных
+// (сохраненных до появления индекса) они заполняются по данным списка.
+func (m *Manager) metadataFor(f models.ExportFile) models.ExportMetadata {
+	if existing, ok := m.index.cached(f.Name, f.MetaVersion); ok && existing != nil {
+		return *existing
+	}
+	if existing := m.lookupMetadata(f.Name); existing != nil {
+		return *existing
+	}
+	return defaultMetadata(f)
+}
+
+// defaultMetadata заполняет метаданные файла без индекса по данным списка
+func defaultMetadata(f models.ExportFile) models.ExportMetadata {
+	return models.ExportMetadata{
+		Filename:    f.Name,
+		ProjectID:   f.ProjectID,
+		GroupName:   f.GroupName,
+		CreatedAt:   f.ModifiedTime,
+		Compression: f.Compression,
+		Size:        f.Size,
+	}
+}
+
+// applyMetadata дополняет список файлов данными из индекса метаданных.
+// Для файлов без метаданных остаются значения, разобранные хранилищем из имени файла.
+// Из хранилища читаются только файлы метаданных, которые появились или изменились
+// с прошлого чтения (по версии из списка), — параллельно, не больше metadataReaders сразу
+func (m *Manager) applyMetadata(files []models.ExportFile) {
+	metas := make([]*models.ExportMetadata, len(files))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, metadataReaders)
+	for i := range files {
+		if files[i].MetaVersion == "" {
+			continue
+		}
+		if meta, ok := m.index.cached(files[i].Name, files[i].MetaVersion); ok {
+			metas[i] = meta
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			meta, err := m.readMetadataVersion(files[i].Name, files[i].MetaVersion)
+			if err != nil {
+				log.Printf("Ошибка чтения метаданных %s: %v", files[i].Name, err)
+			}
+			metas[i] = meta
+		}(i)
+	}
+	wg.Wait()
+
+	for i, meta := range metas {
+		if meta == nil {
+			continue
+		}
+		files[i].ProjectID = meta.ProjectID
+		files[i].GroupID = meta.GroupID
+		files[i].GroupName = meta.GroupName
+		files[i].RunID = meta.RunID
+		files[i].SHA256 = meta.SHA256
+		files[i].Rows = meta.Rows
+		files[i].Compression = meta.Compression
+		files[i].Pinned = meta.Pinned
+		files[i].Snapshot = meta.Snapshot
+		files[i].Quarantined = meta.Quarantined
+		files[i].Problem = meta.Problem
+	}
+}
+
+// removeMetadata удаляет файл метаданных и запись индекса. Ошибка удаления не важна:
+// у старых файлов метаданных нет, а локальная очистка удаляет их вместе с файлами.
+func (m *Manager) removeMetadata(filename string) {
+	m.index.remove(filename)
+	m.storage.DeleteFile(storage.MetaSidecarName(filename))
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"sync"
+	"testing"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// countingStorage считает чтения файлов метаданных и списков
+type countingStorage struct {
+	storage.Storage
+	mu    sync.Mutex
+	reads int
+	lists int
+}
+
+func (s *countingStorage) ListFiles() ([]models.ExportFile, error) {
+	s.mu.Lock()
+	s.lists++
+	s.mu.Unlock()
+	return s.Storage.ListFiles()
+}
+
+func (s *countingStorage) GetFile(filename string) ([]byte, error) {
+	if storage.IsMetaSidecar(filename) {
+		s.mu.Lock()
+		s.reads++
+		s.mu.Unlock()
+	}
+	return s.Storage.GetFile(filename)
+}
+
+// takeReads возвращает число чтений метаданных с прошлого вызова
+func (s *countingStorage) takeReads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reads := s.reads
+	s.reads = 0
+	return reads
+}
+
+func TestListingReadsOnlyChangedMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewManager(&config.Config{ExportPath: dir})
+	names := []string{
+		"testops_export_17_API_2025-07-15_12-02-56.csv",
+		"testops_export_17_UI_2025-07-15_12-02-56.csv",
+		"testops_export_17_Mobile_2025-07-15_12-02-56.csv",
+	}
+	for _, name := range names {
+		if err := writer.storeFile([]byte("ID;Name\n1;Вход\n"), models.ExportMetadata{Filename: name, ProjectID: 17, GroupName: "Группа"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Файл, сохраненный до появления метаданных
+	old := "testops_export_17_Web_2025-07-15_12-02-56.csv"
+	if err := writer.storage.SaveFile([]byte("ID;Name\n"), old, models.ExportMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Другая реплика с пустым кэшем
+	reader := NewManager(&config.Config{ExportPath: dir})
+	counter := &countingStorage{Storage: reader.storage}
+	reader.storage = counter
+
+	files, err := reader.listAllFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("в списке %d файлов, ожидалось 4", len(files))
+	}
+	if reads := counter.takeReads(); reads != len(names) {
+		t.Errorf("первый список прочитал %d файлов метаданных, ожидалось %d", reads, len(names))
+	}
+	for _, f := range files {
+		if f.Name != old && f.GroupName != "Группа" {
+			t.Errorf("%s: группа %q не из метаданных", f.Name, f.GroupName)
+		}
+	}
+
+	if _, err := reader.listAllFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if reads := counter.takeReads(); reads != 0 {
+		t.Errorf("повторный список прочитал %d файлов метаданных, ожидалось 0", reads)
+	}
+
+	// Закрепление на первой реплике меняет один файл метаданных
+	if _, err := writer.SetPinned(names[1], true); err != nil {
+		t.Fatal(err)
+	}
+	files, err = reader.listAllFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads := counter.takeReads(); reads != 1 {
+		t.Errorf("после закрепления прочитано %d файлов метаданных, ожидался 1", reads)
+	}
+	for _, f := range files {
+		if f.Pinned != (f.Name == names[1]) {
+			t.Errorf("%s: закреплен %v", f.Name, f.Pinned)
+		}
+	}
+}
+
+func TestExportFilesWithUsageListsOnce(t *testing.T) {
+	m := NewManager(&config.Config{ExportPath: t.TempDir(), StorageQuota: 1 << 20})
+	for _, f := range []struct {
+		name      string
+		projectID int64
+	}{
+		{"testops_export_17_API_2025-07-15_12-02-56.csv", 17},
+		{"testops_export_17_UI_2025-07-15_12-02-56.csv", 17},
+		{"testops_export_18_API_2025-07-15_12-02-56.csv", 18},
+	} {
+		if err := m.storeFile([]byte("ID;Name\n1;Вход\n"), models.ExportMetadata{Filename: f.name, ProjectID: f.projectID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.CreateSnapshot("v1", []string{"testops_export_18_API_2025-07-15_12-02-56.csv"}); err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingStorage{Storage: m.storage}
+	m.storage = counter
+
+	files, usage, err := m.GetExportFilesWithUsage(17)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("файлов проекта 17: %d, ожидалось 2", len(files))
+	}
+	if usage.Used != 2*int64(len("ID;Name\n1;Вход\n")) {
+		t.Errorf("занято %d байт в проекте 17", usage.Used)
+	}
+
+	files, usage, err = m.GetExportFilesWithUsage(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("файлов без копий в снимке: %d, ожидалось 3", len(files))
+	}
+	if usage.Used != 4*int64(len("ID;Name\n1;Вход\n")) || usage.Quota != 1<<20 {
+		t.Errorf("занято %d из %d байт, копия в снимке тоже занимает место", usage.Used, usage.Quota)
+	}
+	if counter.lists != 2 || counter.reads != 0 {
+		t.Errorf("списков %d, чтений метаданных %d; ожидался один список на вызов без чтений", counter.lists, counter.reads)
+	}
+}
@@ -1,13 +1,12 @@
 package export
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"sort"
-	"strconv"
-	"strings"
 	"time"
 
 	"sync"
@@ -21,11 +20,26 @@ import (
 
 // Manager представляет менеджер экспорта
 type Manager struct {
-	config    *config.Config
-	client    *api.Client
-	s3storage *storage.S3Storage
+	config  *config.Config
+	client  *api.Client
+	storage storage.Storage
+	index   *metadataIndex
+	quotaMu sync.Mutex // Проверка квоты и сохранение выполняются по одному
+	metaMu  sync.Mutex // Изменения метаданных (закрепление, карантин) выполняются по одному
+	auditMu sync.Mutex // Записи журнала аудита не перемешиваются
+	scrub   scrubState
+	runs    *runRegistry // Состояние последних запусков (для API)
 }
 
+// ErrFileNotFound возвращается, если запрошенного файла нет в хранилище
+var ErrFileNotFound = errors.New("файл не найден")
+
+// ErrFileProtected возвращается при попытке удалить копию в снимке релиза или закрепленный файл
+var ErrFileProtected = errors.New("файл защищен от удаления")
+
+// ErrRunExists возвращается при регистрации запуска с уже занятым ID
+var ErrRunExists = errors.New("запуск с таким ID уже существует")
+
 // NextExportInfo содержит информацию о следующем экспорте
 type NextExportInfo struct {
 	NextRunTime      time.Time
@@ -42,45 +56,56 @@ func NewManager(cfg *config.Config) *Manager {
 	if err := os.MkdirAll(cfg.ExportPath, 0755); err != nil {
 		log.Fatalf("Ошибка создания директории экспорта: %v", err)
 	}
-	var s3storage *storage.S3Storage
-	if cfg.S3Enabled {
-		s3, err := storage.NewS3Storage(cfg)
+	st, err := storage.New(cfg)
+	if err != nil {
+		// Если удаленное хранилище недоступно — работаем с локальной директорией
+		log.Printf("Ошибка инициализации хранилища: %v", err)
+		st, err = storage.NewLocalStorage(cfg)
 		if err != nil {
-			log.Printf("Ошибка инициализации S3: %v", err)
-		} else {
-			s3storage = s3
+			log.Fatalf("Ошибка инициализации локального хранилища: %v", err)
 		}
 	}
 	return &Manager{
-		config:    cfg,
-		client:    api.NewClient(cfg),
-		s3storage: s3storage,
+		config:  cfg,
+		client:  api.NewClient(cfg),
+		storage: st,
+		index:   newMetadataIndex(),
+		runs:    newRunRegistry(),
 	}
 }
 
-// PerformExport выполняет экспорт всех групп с повторными попытками
-func (m *Manager) PerformExport() {
+// PerformExport выполняет экспорт всех групп с повторными попытками и возвращает имена сохраненных файлов
+func (m *Manager) PerformExport() []string {
 	// Создаём директорию экспорта, если её нет
 	if err := os.MkdirAll(m.config.ExportPath, 0755); err != nil {
 		log.Fatalf("Ошибка создания директории экспорта: %v", err)
 	}
 
-	log.Println("Начинаем экспорт тесткейсов...")
+	runID := newRunID()
+	if err := m.startRun(runID, 0, nil, m.runTargets(0, nil)); err != nil {
+		log.Printf("❌ %v", err)
+		return nil
+	}
+	log.Printf("Начинаем экспорт тесткейсов (запуск %s)...", runID)
 
 	successCount := 0
 	totalCount := 0
+	var results runResults
 
 	for _, project := range m.config.Projects {
 		for _, group := range project.Groups {
 			totalCount++
-			if err := m.performExportWithRetry(project.ProjectID, project.TreeID, group); err != nil {
+			if res, err := m.performExportWithRetry(runID, project.ProjectID, project.TreeID, group); err != nil {
 				log.Printf("❌ %v", err)
 			} else {
+				results.add(res)
 				successCount++
 			}
 		}
 	}
 
+	m.finishRun(runID, results.list())
+
 	// Очищаем старые файлы только если был хотя бы один успешный экспорт
 	if successCount > 0 {
 		if err := m.cleanupOldExports(); err != nil {
@@ -89,18 +114,25 @@ func (m *Manager) PerformExport() {
 	}
 
 	log.Printf("Экспорт завершен: %d/%d групп успешно", successCount, totalCount)
+	return results.filenames()
 }
 
-// PerformExportForProject выполняет экспорт только для выбранного проекта
-func (m *Manager) PerformExportForProject(projectID int64) {
+// PerformExportForProject выполняет экспорт только для выбранного проекта и возвращает имена сохраненных файлов
+func (m *Manager) PerformExportForProject(projectID int64) []string {
 	if err := os.MkdirAll(m.config.ExportPath, 0755); err != nil {
 		log.Fatalf("Ошибка создания директории экспорта: %v", err)
 	}
 
-	log.Printf("Начинаем экспорт тесткейсов для проекта %d...", projectID)
+	runID := newRunID()
+	if err := m.startRun(runID, projectID, nil, m.runTargets(projectID, nil)); err != nil {
+		log.Printf("❌ %v", err)
+		return nil
+	}
+	log.Printf("Начинаем экспорт тесткейсов для проекта %d (запуск %s)...", projectID, runID)
 
 	successCount := 0
 	totalCount := 0
+	var results runResults
 
 	for _, project := range m.config.Projects {
 		if project.ProjectID != projectID {
@@ -108,14 +140,17 @@ func (m *Manager) PerformExportForProject(projectID int64) {
 		}
 		for _, group := range project.Groups {
 			totalCount++
-			if err := m.performExportWithRetry(project.ProjectID, project.TreeID, group); err != nil {
+			if res, err := m.performExportWithRetry(runID, project.ProjectID, project.TreeID, group); err != nil {
 				log.Printf("❌ %v", err)
 			} else {
+				results.add(res)
 				successCount++
 			}
 		}
 	}
 
+	m.finishRun(runID, results.list())
+
 	if successCount > 0 {
 		if err := m.cleanupOldExports(); err != nil {
 			log.Printf("Ошибка очистки старых файлов: %v", err)
@@ -123,241 +158,293 @@ func (m *Manager) PerformExportForProject(projectID int64) {
 	}
 
 	log.Printf("Экспорт завершен для проекта %d: %d/%d групп успешно", projectID, successCount, totalCount)
+	return results.filenames()
 }
 
-// PerformExportForProjectParallel выполняет экспорт групп проекта параллельно с ограничением на 5 одновременных задач
-func (m *Manager) PerformExportForProjectParallel(projectID int64) {
-	if err := os.MkdirAll(m.config.ExportPath, 0755); err != nil {
-		log.Fatalf("Ошибка создания директории экспорта: %v", err)
+// PerformExportForProjectParallel выполняет экспорт групп проекта параллельно с ограничением на 5 одновременных задач.
+// Возвращает имена сохраненных файлов
+func (m *Manager) PerformExportForProjectParallel(projectID int64) []string {
+	runID := newRunID()
+	targets := m.runTargets(projectID, nil)
+	if err := m.startRun(runID, projectID, nil, targets); err != nil {
+		log.Printf("❌ %v", err)
+		return nil
 	}
+	return m.performExportParallel(runID, projectID, targets)
+}
 
-	log.Printf("Начинаем параллельный экспорт тесткейсов для проекта %d...", projectID)
-
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 5) // максимум 5 одновременных экспортов
-
-	for _, project := range m.config.Projects {
-		if project.ProjectID != projectID {
-			continue
-		}
-		for _, group := range project.Groups {
-			wg.Add(1)
-			go func(projectID int64, treeID int, group models.ExportGroupConfig) {
-				defer wg.Done()
-				semaphore <- struct{}{}        // занять слот
-				defer func() { <-semaphore }() // освободить слот
-
-				if err := m.performExportWithRetry(projectID, treeID, group); err != nil {
-					log.Printf("❌ Группа %s: %v", group.GroupName, err)
-				}
-			}(project.ProjectID, project.TreeID, group)
-		}
+// PerformExportParallel выполняет экспорт всех групп всех проектов параллельно с ограничением на 5 одновременных задач.
+// Возвращает имена сохраненных файлов
+func (m *Manager) PerformExportParallel() []string {
+	runID := newRunID()
+	targets := m.runTargets(0, nil)
+	if err := m.startRun(runID, 0, nil, targets); err != nil {
+		log.Printf("❌ %v", err)
+		return nil
 	}
-	wg.Wait()
-	log.Printf("Параллельный экспорт завершен для проекта %d", projectID)
+	return m.performExportParallel(runID, 0, targets)
 }
 
-// PerformExportParallel выполняет экспорт всех групп всех проектов параллельно с ограничением на 5 одновременных задач
-func (m *Manager) PerformExportParallel() {
+// performExportParallel выполняет параллельный экспорт групп зарегистрированного запуска
+// для проекта projectID (0 — все проекты)
+func (m *Manager) performExportParallel(runID string, projectID int64, targets []runTarget) []string {
 	if err := os.MkdirAll(m.config.ExportPath, 0755); err != nil {
 		log.Fatalf("Ошибка создания директории экспорта: %v", err)
 	}
 
-	log.Println("Начинаем параллельный экспорт тесткейсов для всех проектов...")
+	if projectID != 0 {
+		log.Printf("Начинаем параллельный экспорт тесткейсов для проекта %d (запуск %s)...", projectID, runID)
+	} else {
+		log.Printf("Начинаем параллельный экспорт тесткейсов для всех проектов (запуск %s)...", runID)
+	}
 
 	var wg sync.WaitGroup
+	var results runResults
 	semaphore := make(chan struct{}, 5) // максимум 5 одновременных экспортов
 
-	for _, project := range m.config.Projects {
-		for _, group := range project.Groups {
-			wg.Add(1)
-			go func(projectID int64, treeID int, group models.ExportGroupConfig) {
-				defer wg.Done()
-				semaphore <- struct{}{}        // занять слот
-				defer func() { <-semaphore }() // освободить слот
-
-				if err := m.performExportWithRetry(projectID, treeID, group); err != nil {
-					log.Printf("❌ Проект %d, группа %s: %v", projectID, group.GroupName, err)
-				}
-			}(project.ProjectID, project.TreeID, group)
-		}
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t runTarget) {
+			defer wg.Done()
+			semaphore <- struct{}{}        // занять слот
+			defer func() { <-semaphore }() // освободить слот
+
+			if res, err := m.performExportWithRetry(runID, t.projectID, t.treeID, t.group); err != nil {
+				log.Printf("❌ Проект %d, группа %s: %v", t.projectID, t.group.GroupName, err)
+			} else {
+				results.add(res)
+			}
+		}(t)
 	}
 	wg.Wait()
-	log.Println("Параллельный экспорт завершен для всех проектов")
+	m.finishRun(runID, results.list())
+	if projectID != 0 {
+		log.Printf("Параллельный экспорт завершен для проекта %d", projectID)
+	} else {
+		log.Println("Параллельный экспорт завершен для всех проектов")
+	}
+	return results.filenames()
 }
 
-// performExportWithRetry выполняет экспорт с повторными попытками
-func (m *Manager) performExportWithRetry(projectID int64, treeID int, group models.ExportGroupConfig) error {
+// newRunID возвращает уникальный идентификатор запуска экспорта вида
+// 20250715-070000-123456789-a1b2c3: время UTC с наносекундами и случайный суффикс,
+// чтобы запуски, начатые в одну секунду (в том числе на разных репликах), не совпадали
+func newRunID() string {
+	now := time.Now().UTC()
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%09d-%x", now.Format("20060102-150405"), now.Nanosecond(), suffix)
+}
+
+// performExportWithRetry выполняет экспорт с повторными попытками и учитывает результат в состоянии запуска
+func (m *Manager) performExportWithRetry(runID string, projectID int64, treeID int, group models.ExportGroupConfig) (res *exportResult, err error) {
+	defer func() { m.runs.record(runID, projectID, group, res, err) }()
 	log.Printf("[START] Проект %d, группа %s", projectID, group.GroupName)
 	var lastErr error
+	// retry отмечает ошибку попытки и ждет перед следующей
+	retry := func(attempt int, err error) {
+		lastErr = err
+		log.Printf("[RETRY] Проект %d, группа %s, попытка %d/%d: %v", projectID, group.GroupName, attempt, m.config.MaxRetries, err)
+		if attempt < m.config.MaxRetries {
+			m.runs.progress(runID, projectID, group, StageRetry, attempt+1, err)
+		}
+		time.Sleep(time.Duration(attempt) * m.config.RetryDelay)
+	}
 	for attempt := 1; attempt <= m.config.MaxRetries; attempt++ {
+		m.runs.progress(runID, projectID, group, StageRequesting, attempt, nil)
 		exportResp, err := m.client.RequestExport(projectID, treeID, group.GroupID)
 		if err != nil {
-			lastErr = err
-			log.Printf("[RETRY] Проект %d, группа %s, попытка %d/%d: %v", projectID, group.GroupName, attempt, m.config.MaxRetries, err)
-			time.Sleep(time.Duration(attempt) * m.config.RetryDelay)
+			retry(attempt, err)
 			continue
 		}
 
+		m.runs.progress(runID, projectID, group, StageWaiting, attempt, nil)
 		time.Sleep(5 * time.Second)
 
+		m.runs.progress(runID, projectID, group, StageDownloading, attempt, nil)
 		data, err := m.client.DownloadExport(exportResp.ID)
 		if err != nil {
-			lastErr = err
-			log.Printf("[RETRY] Проект %d, группа %s, попытка %d/%d: %v", projectID, group.GroupName, attempt, m.config.MaxRetries, err)
-			time.Sleep(time.Duration(attempt) * m.config.RetryDelay)
+			retry(attempt, err)
 			continue
 		}
 
-		if err := m.saveExport(data, group.GroupName, projectID); err != nil {
-			lastErr = err
-			log.Printf("[RETRY] Проект %d, группа %s, попытка %d/%d: %v", projectID, group.GroupName, attempt, m.config.MaxRetries, err)
-			time.Sleep(time.Duration(attempt) * m.config.RetryDelay)
+		res, err := m.saveExport(data, group, projectID, runID, exportResp.ID)
+		if errors.Is(err, errQuotaExceeded) {
+			log.Printf("[FAIL]  Проект %d, группа %s: %v", projectID, group.GroupName, err)
+			return nil, err
+		}
+		if err != nil {
+			retry(attempt, err)
 			continue
 		}
 
-		filename := m.makeExportFilename(group.GroupName, projectID)
-		log.Printf("[OK]    Проект %d, группа %s, файл: %s", projectID, group.GroupName, filename)
-		return nil
+		log.Printf("[OK]    Проект %d, группа %s, файл: %s", projectID, group.GroupName, res.File.Name)
+		return res, nil
 	}
 	log.Printf("[FAIL]  Проект %d, группа %s, попытка %d/%d: %v", projectID, group.GroupName, m.config.MaxRetries, m.config.MaxRetries, lastErr)
-	return lastErr
-}
-
-// makeExportFilename возвращает имя файла экспорта для логов
-func (m *Manager) makeExportFilename(groupName string, projectID int64) string {
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	return fmt.Sprintf("testops_export_%d_%s_%s.csv", projectID, groupName, timestamp)
+	return nil, lastErr
 }
 
-// saveExport сохраняет экспорт в файл
-func (m *Manager) saveExport(data []byte, groupName string, projectID int64) error {
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("testops_export_%d_%s_%s.csv", projectID, groupName, timestamp)
-	filepathOnDisk := filepath.Join(m.config.ExportPath, filename)
+// saveExport сохраняет экспорт в хранилище и возвращает сохраненный результат
+func (m *Manager) saveExport(data []byte, group models.ExportGroupConfig, projectID int64, runID string, exportID int) (*exportResult, error) {
+	now := time.Now()
+	filename := m.exportFilename(projectID, group, runID, now)
 
-	if m.config.S3Enabled && m.s3storage != nil {
-		err := m.s3storage.SaveFile(data, filename)
-		if err != nil {
-			log.Printf("Ошибка сохранения в S3: %v", err)
-			return err
-		}
-		return nil
+	payload, err := storage.Compress(data, m.config.Compression)
+	if err != nil {
+		return nil, err
 	}
 
-	// Если S3 не включён — сохраняем локально
-	if err := os.MkdirAll(m.config.ExportPath, 0755); err != nil {
-		return fmt.Errorf("ошибка создания директории: %v", err)
+	file := describeFile(filename, payload, data)
+	file.ProjectID = projectID
+	file.GroupID = group.GroupID
+	file.GroupName = group.GroupName
+	file.ExportID = exportID
+	file.Compression = m.config.Compression
+
+	meta := models.ExportMetadata{
+		Filename:    filename,
+		ProjectID:   projectID,
+		GroupID:     group.GroupID,
+		GroupName:   group.GroupName,
+		RunID:       runID,
+		CreatedAt:   now,
+		Compression: m.config.Compression,
+		ExportID:    exportID,
+		Rows:        file.Rows,
+		Header:      file.Header,
 	}
-	if err := os.WriteFile(filepathOnDisk, data, 0644); err != nil {
-		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	if err := m.storeFile(payload, meta); err != nil {
+		log.Printf("Ошибка сохранения в хранилище: %v", err)
+		return nil, err
 	}
-	return nil
+	res := &exportResult{File: file, CreatedAt: now}
+	if m.config.Bundle != "" {
+		// Несжатый CSV нужен только для ZIP архива запуска, без архивов не держим его в памяти
+		res.Data = data
+	}
+	return res, nil
 }
 
 // GetExportFiles возвращает список файлов экспорта
+// (копии файлов в снимках релизов не включаются, см. GetSnapshots)
 func (m *Manager) GetExportFiles(projectIDFilter ...int64) ([]models.ExportFile, error) {
-	var exportFiles []models.ExportFile
-	var err error
+	allFiles, err := m.listAllFiles()
+	if err != nil {
+		return nil, err
+	}
+	projectID := int64(0)
+	if len(projectIDFilter) > 0 {
+		projectID = projectIDFilter[0]
+	}
+	return exportFiles(allFiles, len(projectIDFilter) > 0, projectID), nil
+}
 
-	if m.config.S3Enabled && m.s3storage != nil {
-		exportFiles, err = m.s3storage.ListFiles()
-		if err != nil {
-			return nil, err
+// GetExportFilesWithUsage возвращает файлы экспорта проекта (всех проектов, если projectID 0) и занятое
+// место по одному списку хранилища — для главной страницы, чтобы не перечитывать список дважды
+func (m *Manager) GetExportFilesWithUsage(projectID int64) ([]models.ExportFile, models.StorageUsage, error) {
+	allFiles, err := m.listAllFiles()
+	if err != nil {
+		return nil, models.StorageUsage{}, err
+	}
+	return exportFiles(allFiles, projectID != 0, projectID), m.storageUsage(allFiles, projectID), nil
+}
+
+// exportFiles отбирает из списка хранилища файлы экспорта без копий в снимках
+func exportFiles(allFiles []models.ExportFile, byProject bool, projectID int64) []models.ExportFile {
+	var files []models.ExportFile
+	for _, f := range allFiles {
+		if f.Snapshot != "" {
+			continue
 		}
-	} else {
-		files, err := os.ReadDir(m.config.ExportPath)
-		if err != nil {
-			return nil, err
+		// Фильтрация по projectID, если передан
+		if byProject && f.ProjectID != projectID {
+			continue
 		}
+		files = append(files, f)
+	}
+	return files
+}
 
-		for _, file := range files {
-			if file.IsDir() || !strings.HasSuffix(file.Name(), ".csv") {
-				continue
-			}
-
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
+// GetExportFile возвращает файл хранилища и его метаданные. Для файлов без индекса
+// метаданные заполняются по данным списка
+func (m *Manager) GetExportFile(filename string) (models.ExportFile, models.ExportMetadata, error) {
+	files, err := m.listAllFiles()
+	if err != nil {
+		return models.ExportFile{}, models.ExportMetadata{}, err
+	}
+	for _, f := range files {
+		if f.Name == filename {
+			return f, m.metadataFor(f), nil
+		}
+	}
+	return models.ExportFile{}, models.ExportMetadata{}, fmt.Errorf("%w: %s", ErrFileNotFound, filename)
+}
 
-			// Парсим ProjectID из имени файла
-			var projectID int64 = 0
-			parts := strings.Split(file.Name(), "_")
-			if len(parts) > 2 {
-				projectID, err = strconv.ParseInt(parts[2], 10, 64)
-				if err != nil {
-					continue // если не удалось распарсить projectID, пропускаем файл
-				}
-			} else {
-				continue // если формат имени файла не подходит, пропускаем файл
-			}
+// FileProjectID возвращает ID проекта файла из метаданных, а для файлов без них — из имени.
+// 0 — файл всех проектов (или проект не удалось определить)
+func (m *Manager) FileProjectID(filename string) int64 {
+	if meta := m.lookupMetadata(filename); meta != nil {
+		return meta.ProjectID
+	}
+	return storage.ProjectFromFilename(filename)
+}
 
-			exportFile := models.ExportFile{
-				Name:          file.Name(),
-				Size:          info.Size(),
-				ModifiedTime:  info.ModTime(),
-				FormattedSize: m.FormatFileSize(info.Size()),
-				FormattedDate: info.ModTime().Format("02.01.2006 15:04:05"),
-				ProjectID:     projectID,
-			}
-			exportFiles = append(exportFiles, exportFile)
+// listAllFiles возвращает все файлы хранилища с данными из индекса метаданных (новые сверху)
+func (m *Manager) listAllFiles() ([]models.ExportFile, error) {
+	listed, err := m.storage.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	// Служебные файлы (состояние запусков, отчет проверки) в списках файлов, квотах и проверках не участвуют
+	files := listed[:0:0]
+	for _, f := range listed {
+		if !storage.IsServiceFile(f.Name) {
+			files = append(files, f)
 		}
 	}
+	m.applyMetadata(files)
 
-	// Фильтрация по projectID, если передан (работает и для S3, и для локальных файлов)
-	if len(projectIDFilter) > 0 {
-		pid := projectIDFilter[0]
-		var filtered []models.ExportFile
-		for _, f := range exportFiles {
-			if f.ProjectID == pid {
-				filtered = append(filtered, f)
-			}
+	for i := range files {
+		files[i].Bundle = storage.IsBundle(files[i].Name)
+		files[i].Manifest = storage.IsManifest(files[i].Name)
+		if files[i].Snapshot == "" && storage.IsSnapshotFile(files[i].Name) {
+			files[i].Snapshot = storage.SnapshotLabel(files[i].Name)
 		}
-		exportFiles = filtered
 	}
 
 	// Сортируем по дате изменения (новые сверху)
-	sort.Slice(exportFiles, func(i, j int) bool {
-		return exportFiles[i].ModifiedTime.After(exportFiles[j].ModifiedTime)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModifiedTime.After(files[j].ModifiedTime)
 	})
 
-	return exportFiles, nil
+	return files, nil
 }
 
-// cleanupOldExports удаляет файлы старше месяца
+// cleanupOldExports удаляет незакрепленные файлы старше месяца вместе с их метаданными
 func (m *Manager) cleanupOldExports() error {
-	if m.config.S3Enabled && m.s3storage != nil {
-		return m.s3storage.CleanupOldFiles()
+	before, err := m.storage.ListFiles()
+	if err != nil {
+		return err
+	}
+	if err := m.storage.CleanupOldFiles(m.keepFile); err != nil {
+		return err
 	}
-	// Локальный режим
-	files, err := os.ReadDir(m.config.ExportPath)
+	after, err := m.storage.ListFiles()
 	if err != nil {
-		return fmt.Errorf("ошибка чтения директории: %v", err)
+		return err
 	}
 
-	monthAgo := time.Now().AddDate(0, -1, 0)
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		info, err := file.Info()
-		if err != nil {
-			continue
-		}
-
-		if info.ModTime().Before(monthAgo) {
-			filepath := filepath.Join(m.config.ExportPath, file.Name())
-			if err := os.Remove(filepath); err != nil {
-				log.Printf("Ошибка удаления старого файла %s: %v", filepath, err)
-			} else {
-				log.Printf("Удален старый файл: %s", filepath)
-			}
+	remaining := make(map[string]bool, len(after))
+	for _, f := range after {
+		remaining[f.Name] = true
+	}
+	for _, f := range before {
+		if !remaining[f.Name] {
+			m.removeMetadata(f.Name)
 		}
 	}
-
+	m.index.retain(after)
 	return nil
 }
 
@@ -377,22 +464,51 @@ func (m *Manager) FormatFileSize(size int64) string {
 
 // DownloadExportFile возвращает содержимое файла экспорта
 func (m *Manager) DownloadExportFile(filename string) ([]byte, error) {
-	if m.config.S3Enabled && m.s3storage != nil {
-		return m.s3storage.GetFile(filename)
+	return m.storage.GetFile(filename)
+}
+
+// DownloadDecompressedExportFile возвращает содержимое файла экспорта, распаковывая сжатые файлы
+func (m *Manager) DownloadDecompressedExportFile(filename string) ([]byte, error) {
+	data, err := m.storage.GetFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Decompress(data, storage.CompressionFromName(filename))
+}
+
+// PresignedDownloadURL возвращает временную ссылку на скачивание файла напрямую из хранилища.
+// Если ссылки отключены, не поддерживаются хранилищем или не удалось их создать, возвращает false —
+// тогда файл нужно отдавать через сервер.
+func (m *Manager) PresignedDownloadURL(filename string) (string, bool) {
+	if !m.config.S3PresignDownloads {
+		return "", false
+	}
+	presigner, ok := m.storage.(storage.Presigner)
+	if !ok {
+		return "", false
+	}
+	url, err := presigner.PresignGetURL(filename, m.config.S3PresignExpiry)
+	if err != nil {
+		log.Printf("Ошибка создания временной ссылки для %s, отдаем файл через сервер: %v", filename, err)
+		return "", false
 	}
-	// Локальный режим
-	filePath := filepath.Join(m.config.ExportPath, filename)
-	return os.ReadFile(filePath)
+	return url, true
 }
 
-// DeleteExportFile удаляет файл экспорта
+// DeleteExportFile удаляет файл экспорта вместе с его метаданными.
+// Копии файлов в снимках релизов неизменяемы и не удаляются, закрепленные файлы нужно сначала открепить.
 func (m *Manager) DeleteExportFile(filename string) error {
-	if m.config.S3Enabled && m.s3storage != nil {
-		return m.s3storage.DeleteFile(filename)
+	if storage.IsSnapshotFile(filename) {
+		return fmt.Errorf("%w: %s входит в снимок релиза", ErrFileProtected, filename)
 	}
-	// Локальный режим
-	filePath := filepath.Join(m.config.ExportPath, filename)
-	return os.Remove(filePath)
+	if m.isPinned(filename) {
+		return fmt.Errorf("%w: %s закреплен, сначала открепите его", ErrFileProtected, filename)
+	}
+	if err := m.storage.DeleteFile(filename); err != nil {
+		return err
+	}
+	m.removeMetadata(filename)
+	return nil
 }
 
 // Config возвращает конфиг менеджера
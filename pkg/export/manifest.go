@@ -0,0 +1,203 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"testops-export/pkg/api"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// Статусы проверки файлов по манифесту
+const (
+	verifyOK       = "ok"
+	verifyMismatch = "mismatch"
+	verifyMissing  = "missing"
+)
+
+// describeFile описывает файл для манифеста: размер и SHA-256 хранимых данных,
+// а для CSV — заголовок и количество строк (csvData — несжатое содержимое, nil для не-CSV)
+func describeFile(name string, stored, csvData []byte) models.ManifestFile {
+	file := models.ManifestFile{
+		Name:   name,
+		Size:   int64(len(stored)),
+		SHA256: sha256Hex(stored),
+	}
+	if csvData != nil {
+		header, rows, err := csvStats(csvData)
+		if err != nil {
+			log.Printf("Не удалось разобрать CSV %s для манифеста: %v", name, err)
+		}
+		file.Header = header
+		file.Rows = rows
+	}
+	return file
+}
+
+// sha256Hex возвращает SHA-256 данных в hex
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newCSVReader создает чтение CSV экспорта TestOps: разделитель «;», BOM в начале пропускается
+func newCSVReader(data []byte) *csv.Reader {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.Comma = ';'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r
+}
+
+// csvStats возвращает заголовок CSV экспорта TestOps и количество строк без заголовка
+func csvStats(data []byte) ([]string, int, error) {
+	r := newCSVReader(data)
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := 0
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return header, rows, err
+		}
+		rows++
+	}
+	return header, rows, nil
+}
+
+// ParseCSV разбирает CSV экспорта TestOps и возвращает заголовок и строки без заголовка.
+// Строки дополняются пустыми значениями до числа колонок заголовка
+func ParseCSV(data []byte) ([]string, [][]string, error) {
+	r := newCSVReader(data)
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка разбора CSV: %v", err)
+	}
+
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return header, rows, fmt.Errorf("ошибка разбора CSV: %v", err)
+		}
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// saveRunManifest сохраняет манифест запуска со списком всех созданных файлов
+func (m *Manager) saveRunManifest(runID string, files []models.ManifestFile) {
+	// Если запуск касался одного проекта, манифест относится к нему
+	var projectID int64
+	for i, f := range files {
+		if i == 0 {
+			projectID = f.ProjectID
+		} else if f.ProjectID != projectID {
+			projectID = 0
+			break
+		}
+	}
+
+	now := time.Now()
+	manifest := models.RunManifest{
+		RunID:     runID,
+		CreatedAt: now.UTC(),
+		ProjectID: projectID,
+		Mapping:   api.ExportMapping(),
+		Files:     files,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		log.Printf("❌ Ошибка формирования манифеста запуска %s: %v", runID, err)
+		return
+	}
+
+	filename := storage.ManifestFilename(projectID, runID)
+	meta := models.ExportMetadata{
+		Filename:  filename,
+		ProjectID: projectID,
+		GroupName: storage.ManifestGroupName,
+		RunID:     runID,
+		CreatedAt: now,
+	}
+	if err := m.storeFile(data, meta); err != nil {
+		log.Printf("❌ Ошибка сохранения манифеста %s: %v", filename, err)
+		return
+	}
+	log.Printf("[OK]    Манифест запуска %s, файлов: %d", filename, len(files))
+}
+
+// VerifyManifest пересчитывает SHA-256 и размер файлов запуска и сравнивает их с манифестом
+func (m *Manager) VerifyManifest(filename string) (*models.VerifyReport, error) {
+	if !storage.IsManifest(filename) {
+		return nil, fmt.Errorf("файл не является манифестом запуска: %s", filename)
+	}
+	data, err := m.storage.GetFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения манифеста %s: %v", filename, err)
+	}
+	var manifest models.RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("ошибка разбора манифеста %s: %v", filename, err)
+	}
+
+	report := &models.VerifyReport{
+		Manifest:  filename,
+		RunID:     manifest.RunID,
+		CheckedAt: time.Now().UTC(),
+		OK:        true,
+	}
+	for _, f := range manifest.Files {
+		result := models.VerifyResult{
+			Name:           f.Name,
+			ExpectedSHA256: f.SHA256,
+			ExpectedSize:   f.Size,
+		}
+
+		stored, err := m.storage.GetFile(f.Name)
+		if err != nil {
+			result.Status = verifyMissing
+			result.Error = err.Error()
+		} else {
+			result.ActualSHA256 = sha256Hex(stored)
+			result.ActualSize = int64(len(stored))
+			result.Status = verifyOK
+			if result.ActualSHA256 != f.SHA256 || result.ActualSize != f.Size {
+				result.Status = verifyMismatch
+			}
+		}
+
+		if result.Status != verifyOK {
+			report.OK = false
+			log.Printf("⚠️ Проверка %s: файл %s — %s", filename, f.Name, result.Status)
+		}
+		report.Files = append(report.Files, result)
+	}
+	return report, nil
+}
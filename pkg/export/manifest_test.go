@@ -0,0 +1,93 @@
+package export
+
+import (
+	"encoding/json"
+	"testing"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	m := NewManager(&config.Config{ExportPath: t.TempDir()})
+	save := func(name string, data []byte) {
+		t.Helper()
+		if err := m.storage.SaveFile(data, name, models.ExportMetadata{ProjectID: 17}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	good := []byte("ID;Name\n1;Вход\n")
+	changed := []byte("ID;Name\n1;Выход\n")
+	save("testops_export_17_API_2025-07-15_12-02-56.csv", good)
+	save("testops_export_17_UI_2025-07-15_12-02-56.csv", changed)
+	save("testops_export_17_Mobile_2025-07-15_12-02-56.csv", good[:len(good)-1])
+
+	entry := func(name string) models.ManifestFile {
+		return models.ManifestFile{Name: name, ProjectID: 17, Size: int64(len(good)), SHA256: sha256Hex(good)}
+	}
+	manifest := models.RunManifest{RunID: "20250715-120256-000000001-a1b2c3", Files: []models.ManifestFile{
+		entry("testops_export_17_API_2025-07-15_12-02-56.csv"),
+		entry("testops_export_17_UI_2025-07-15_12-02-56.csv"),
+		entry("testops_export_17_Mobile_2025-07-15_12-02-56.csv"),
+		entry("testops_export_17_Web_2025-07-15_12-02-56.csv"),
+	}}
+	data, _ := json.Marshal(manifest)
+	manifestName := storage.ManifestFilename(17, manifest.RunID)
+	save(manifestName, data)
+
+	report, err := m.VerifyManifest(manifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK {
+		t.Error("отчет с поврежденными файлами не должен быть OK")
+	}
+	if report.RunID != manifest.RunID {
+		t.Errorf("run_id %q, ожидался %q", report.RunID, manifest.RunID)
+	}
+
+	want := []struct {
+		name   string
+		status string
+	}{
+		{"testops_export_17_API_2025-07-15_12-02-56.csv", verifyOK},
+		{"testops_export_17_UI_2025-07-15_12-02-56.csv", verifyMismatch},     // Та же длина, другое содержимое
+		{"testops_export_17_Mobile_2025-07-15_12-02-56.csv", verifyMismatch}, // Обрезан
+		{"testops_export_17_Web_2025-07-15_12-02-56.csv", verifyMissing},
+	}
+	if len(report.Files) != len(want) {
+		t.Fatalf("проверено %d файлов, ожидалось %d", len(report.Files), len(want))
+	}
+	for i, w := range want {
+		got := report.Files[i]
+		if got.Name != w.name || got.Status != w.status {
+			t.Errorf("файл %d: %s — %s, ожидалось %s — %s", i, got.Name, got.Status, w.name, w.status)
+		}
+	}
+}
+
+func TestVerifyManifestErrors(t *testing.T) {
+	m := NewManager(&config.Config{ExportPath: t.TempDir()})
+	broken := storage.ManifestFilename(0, "20250715-120256-000000001-a1b2c3")
+	if err := m.storage.SaveFile([]byte("{не json"), broken, models.ExportMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+	}{
+		{"не манифест", "testops_export_17_API_2025-07-15_12-02-56.csv"},
+		{"нет файла", storage.ManifestFilename(17, "20250101-000000-000000001-000000")},
+		{"не разбирается", broken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.VerifyManifest(tt.filename); err == nil {
+				t.Errorf("VerifyManifest(%s) должен вернуть ошибку", tt.filename)
+			}
+		})
+	}
+}
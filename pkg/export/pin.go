@@ -0,0 +1,83 @@
+package export
+
+import (
+	"fmt"
+	"log"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// isPinned проверяет, закреплен ли файл (закрепленные файлы не удаляются автоочисткой).
+// Метаданные читаются из хранилища, а не из кэша: файл могли закрепить на другой реплике.
+// Если метаданные не удалось прочитать, файл считается закрепленным — удалять его небезопасно
+func (m *Manager) isPinned(filename string) bool {
+	meta, err := m.readMetadata(filename)
+	if err != nil {
+		log.Printf("Не удалось проверить закрепление %s, файл не удаляется: %v", filename, err)
+		return true
+	}
+	return meta != nil && meta.Pinned
+}
+
+// keepFile проверяет, что файл нельзя удалять автоочисткой и вытеснением по квоте. Копии в снимках
+// релизов защищены по имени, даже если их метаданные потеряны, закрепленные файлы — по метаданным
+func (m *Manager) keepFile(filename string) bool {
+	return storage.IsSnapshotFile(filename) || m.isPinned(filename)
+}
+
+// SetPinned закрепляет или открепляет файл. Возвращает имена измененных файлов.
+func (m *Manager) SetPinned(filename string, pinned bool) ([]string, error) {
+	return m.setPinned(pinned, func(f models.ExportFile) bool { return f.Name == filename })
+}
+
+// SetRunPinned закрепляет или открепляет все файлы запуска. Возвращает имена измененных файлов.
+func (m *Manager) SetRunPinned(runID string, pinned bool) ([]string, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("ID запуска не указан")
+	}
+	return m.setPinned(pinned, func(f models.ExportFile) bool { return f.RunID == runID })
+}
+
+// setPinned меняет признак закрепления в метаданных выбранных файлов
+func (m *Manager) setPinned(pinned bool, match func(models.ExportFile) bool) ([]string, error) {
+	files, err := m.GetExportFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	matched := 0
+	for _, f := range files {
+		if !match(f) {
+			continue
+		}
+		matched++
+
+		updated, err := m.updateMetadata(f, func(meta *models.ExportMetadata) bool {
+			if meta.Pinned == pinned {
+				return false
+			}
+			meta.Pinned = pinned
+			return true
+		})
+		if err != nil {
+			return changed, err
+		}
+		if updated {
+			changed = append(changed, f.Name)
+		}
+	}
+
+	if matched == 0 {
+		return nil, fmt.Errorf("файлы не найдены")
+	}
+	for _, name := range changed {
+		if pinned {
+			log.Printf("📌 Файл закреплен: %s", name)
+		} else {
+			log.Printf("Файл откреплен: %s", name)
+		}
+	}
+	return changed, nil
+}
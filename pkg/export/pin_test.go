@@ -0,0 +1,145 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// fakeS3 — минимальный S3 (path-style): PUT, GET, DELETE объектов и ListObjectsV2.
+// Все объекты считаются измененными modified
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// /<бакет>[/<ключ>]
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type object struct {
+		Key          string
+		LastModified string
+		Size         int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		IsTruncated bool
+		Contents    []object
+	}{}
+	for key, data := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, object{key, f.modified.Format(time.RFC3339), len(data)})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	xml.NewEncoder(w).Encode(result)
+}
+
+// keys возвращает ключи объектов
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestPinFileWithoutMetadataSurvivesCleanup(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte), modified: time.Now().AddDate(0, -2, 0)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	newReplica := func() *Manager {
+		return NewManager(&config.Config{
+			ExportPath:    t.TempDir(),
+			S3Enabled:     true,
+			S3Endpoint:    srv.URL,
+			S3Region:      "us-east-1",
+			S3Bucket:      "exports",
+			S3AccessKey:   "test",
+			S3SecretKey:   "test",
+			S3KeyTemplate: "{project}/{group_id}/{run_id}/{yyyy}/{filename}",
+		})
+	}
+	first, second := newReplica(), newReplica()
+
+	// Файлы без метаданных (сохранены до их появления): ключ построен по данным,
+	// которых нет в списке файлов, — группе и запуску
+	pinned := "testops_export_17_API_2025-07-15_12-02-56.csv"
+	other := "testops_export_17_UI_2025-07-15_12-02-56.csv"
+	for _, name := range []string{pinned, other} {
+		meta := models.ExportMetadata{ProjectID: 17, GroupID: 5, RunID: "20250715-120256", CreatedAt: fake.modified}
+		if err := first.storage.SaveFile([]byte("ID;Name\n1;Вход\n"), name, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Вторая реплика видела файлы до закрепления
+	if _, err := second.listAllFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := first.SetPinned(pinned, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range fake.keys() {
+		if strings.HasSuffix(key, ".meta.json") && key != "17/5/20250715-120256/"+fake.modified.Format("2006")+"/"+pinned+".meta.json" {
+			t.Errorf("метаданные сохранены не рядом с файлом: %s", key)
+		}
+	}
+
+	if err := second.DeleteExportFile(pinned); !errors.Is(err, ErrFileProtected) {
+		t.Errorf("удаление закрепленного файла на другой реплике: %v, ожидалась ErrFileProtected", err)
+	}
+	if err := second.cleanupOldExports(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := first.listAllFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != pinned || !files[0].Pinned {
+		t.Errorf("после очистки остались %v, ожидался только закрепленный %s", files, pinned)
+	}
+}
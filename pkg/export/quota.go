@@ -0,0 +1,111 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"testops-export/pkg/models"
+)
+
+// errQuotaExceeded возвращается, если файл не помещается в квоту даже после вытеснения старых файлов.
+// Такую ошибку нет смысла повторять.
+var errQuotaExceeded = errors.New("превышена квота хранилища")
+
+// projectQuota возвращает квоту проекта в байтах (0 — без ограничения)
+func (m *Manager) projectQuota(projectID int64) int64 {
+	if projectID == 0 {
+		return 0
+	}
+	for _, p := range m.config.Projects {
+		if p.ProjectID == projectID && p.QuotaBytes > 0 {
+			return p.QuotaBytes
+		}
+	}
+	return m.config.ProjectQuota
+}
+
+// ensureQuota освобождает место под файл проекта projectID размером size, удаляя самые старые
+// незакрепленные файлы: сначала для общей квоты, затем для квоты проекта
+func (m *Manager) ensureQuota(projectID int64, size int64) error {
+	globalQuota := m.config.StorageQuota
+	projectQuota := m.projectQuota(projectID)
+	if globalQuota == 0 && projectQuota == 0 {
+		return nil
+	}
+
+	// Файл больше самой квоты — вытеснять бесполезно
+	if (globalQuota > 0 && size > globalQuota) || (projectQuota > 0 && size > projectQuota) {
+		return fmt.Errorf("%w: файл %s больше квоты", errQuotaExceeded, m.FormatFileSize(size))
+	}
+
+	files, err := m.listAllFiles()
+	if err != nil {
+		return fmt.Errorf("ошибка подсчета занятого места: %v", err)
+	}
+	var used, projectUsed int64
+	for _, f := range files {
+		used += f.Size
+		if f.ProjectID == projectID {
+			projectUsed += f.Size
+		}
+	}
+
+	overGlobal := func() bool { return globalQuota > 0 && used+size > globalQuota }
+	overProject := func() bool { return projectQuota > 0 && projectUsed+size > projectQuota }
+
+	// Файлы отсортированы от новых к старым — вытесняем с конца
+	for i := len(files) - 1; i >= 0 && (overGlobal() || overProject()); i-- {
+		f := files[i]
+		if f.Pinned || f.Snapshot != "" {
+			continue
+		}
+		if !overGlobal() && f.ProjectID != projectID {
+			continue
+		}
+		// Список мог устареть: файл закрепили на другой реплике
+		if m.keepFile(f.Name) {
+			continue
+		}
+		if err := m.DeleteExportFile(f.Name); err != nil {
+			log.Printf("Ошибка вытеснения файла %s: %v", f.Name, err)
+			continue
+		}
+		log.Printf("🧹 Вытеснен по квоте: %s (%s)", f.Name, f.FormattedSize)
+		used -= f.Size
+		if f.ProjectID == projectID {
+			projectUsed -= f.Size
+		}
+	}
+
+	if overGlobal() {
+		return fmt.Errorf("%w: занято %s из %s, файл %s", errQuotaExceeded,
+			m.FormatFileSize(used), m.FormatFileSize(globalQuota), m.FormatFileSize(size))
+	}
+	if overProject() {
+		return fmt.Errorf("%w проекта %d: занято %s из %s, файл %s", errQuotaExceeded, projectID,
+			m.FormatFileSize(projectUsed), m.FormatFileSize(projectQuota), m.FormatFileSize(size))
+	}
+	return nil
+}
+
+// storageUsage возвращает занятое место и квоту: общую или проекта (если projectID не 0)
+// по списку всех файлов хранилища
+func (m *Manager) storageUsage(files []models.ExportFile, projectID int64) models.StorageUsage {
+	usage := models.StorageUsage{Quota: m.config.StorageQuota}
+	if projectID != 0 {
+		usage.Quota = m.projectQuota(projectID)
+	}
+	for _, f := range files {
+		if projectID == 0 || f.ProjectID == projectID {
+			usage.Used += f.Size
+		}
+	}
+
+	usage.FormattedUsed = m.FormatFileSize(usage.Used)
+	if usage.Quota > 0 {
+		usage.FormattedQuota = m.FormatFileSize(usage.Quota)
+		usage.Percent = int(usage.Used * 100 / usage.Quota)
+	}
+	return usage
+}
@@ -0,0 +1,348 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"testops-export/pkg/models"
+)
+
+// Статусы запуска экспорта
+const (
+	RunRunning = "running"
+	RunSuccess = "success" // Все группы выгружены
+	RunPartial = "partial" // Часть групп завершилась ошибкой
+	RunFailed  = "failed"  // Ни одна группа не выгружена
+)
+
+// Этапы экспорта группы в запуске
+const (
+	StageQueued      = "queued"      // Ждет свободного слота
+	StageRequesting  = "requesting"  // Запрос экспорта в TestOps
+	StageWaiting     = "waiting"     // Ожидание готовности файла
+	StageDownloading = "downloading" // Скачивание и сохранение
+	StageSaved       = "saved"
+	StageRetry       = "retry" // Пауза перед следующей попыткой
+	StageFailed      = "failed"
+)
+
+// maxRuns — сколько последних запусков хранится в памяти и возвращается списком запусков
+const maxRuns = 100
+
+// runRegistry хранит состояние запусков экспорта этого экземпляра. Оно же сохраняется
+// в хранилище (см. persistRun), откуда запуски читают остальные реплики
+type runRegistry struct {
+	mu      sync.Mutex
+	runs    map[string]*models.RunStatus
+	changed chan struct{} // Закрывается при любом изменении состояния запусков
+
+	// Завершенные запуски других реплик, прочитанные из хранилища (больше не меняются)
+	stored map[string]models.RunStatus
+}
+
+func newRunRegistry() *runRegistry {
+	return &runRegistry{
+		runs:    make(map[string]*models.RunStatus),
+		changed: make(chan struct{}),
+		stored:  make(map[string]models.RunStatus),
+	}
+}
+
+// notify будит всех, кто ждет изменений (вызывается под mu)
+func (r *runRegistry) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// start регистрирует новый запуск, все группы которого ждут очереди.
+// Существующий запуск с тем же ID не перезаписывается
+func (r *runRegistry) start(runID string, projectID int64, groupIDs []int, groups []models.GroupProgress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.runs[runID]; exists {
+		return fmt.Errorf("%w: %s", ErrRunExists, runID)
+	}
+	r.runs[runID] = &models.RunStatus{
+		RunID:     runID,
+		ProjectID: projectID,
+		GroupIDs:  groupIDs,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+		Total:     len(groups),
+		Files:     []string{},
+		Groups:    groups,
+	}
+	r.trim()
+	r.notify()
+	return nil
+}
+
+// progress отмечает этап экспорта группы
+func (r *runRegistry) progress(runID string, projectID int64, group models.ExportGroupConfig, stage string, attempt int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g := r.group(runID, projectID, group.GroupID)
+	if g == nil {
+		return
+	}
+	g.Stage, g.Attempt, g.UpdatedAt = stage, attempt, time.Now()
+	if err != nil {
+		g.Error = err.Error()
+	}
+	r.notify()
+}
+
+// group возвращает ход экспорта группы запуска (вызывается под mu)
+func (r *runRegistry) group(runID string, projectID int64, groupID int) *models.GroupProgress {
+	run, ok := r.runs[runID]
+	if !ok {
+		return nil
+	}
+	for i := range run.Groups {
+		if run.Groups[i].ProjectID == projectID && run.Groups[i].GroupID == groupID {
+			return &run.Groups[i]
+		}
+	}
+	return nil
+}
+
+// snapshot возвращает копию состояния запуска этого экземпляра и канал, который закроется
+// при следующем изменении состояния запусков
+func (r *runRegistry) snapshot(runID string) (models.RunStatus, <-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return models.RunStatus{}, nil, false
+	}
+	return copyRun(run), r.changed, true
+}
+
+// lastGroupRun возвращает ID и ход группы в ее последнем запуске из runs (новые первыми)
+func lastGroupRun(runs []models.RunStatus, projectID int64, groupID int) (string, *models.GroupProgress) {
+	for _, run := range runs {
+		for _, g := range run.Groups {
+			if g.ProjectID == projectID && g.GroupID == groupID {
+				progress := g
+				return run.RunID, &progress
+			}
+		}
+	}
+	return "", nil
+}
+
+// record учитывает результат экспорта группы
+func (r *runRegistry) record(runID string, projectID int64, group models.ExportGroupConfig, res *exportResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return
+	}
+	defer r.notify()
+	g := r.group(runID, projectID, group.GroupID)
+	if err != nil {
+		run.Failed++
+		run.Errors = append(run.Errors, models.RunError{
+			ProjectID: projectID,
+			GroupID:   group.GroupID,
+			GroupName: group.GroupName,
+			Error:     err.Error(),
+		})
+		if g != nil {
+			g.Stage, g.Error, g.UpdatedAt = StageFailed, err.Error(), time.Now()
+		}
+		return
+	}
+	run.Succeeded++
+	run.Files = append(run.Files, res.File.Name)
+	if g != nil {
+		g.Stage, g.File, g.Error, g.UpdatedAt = StageSaved, res.File.Name, "", time.Now()
+	}
+}
+
+// finish отмечает завершение запуска
+func (r *runRegistry) finish(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	run.FinishedAt = &now
+	switch {
+	case run.Failed == 0:
+		run.Status = RunSuccess
+	case run.Succeeded > 0:
+		run.Status = RunPartial
+	default:
+		run.Status = RunFailed
+	}
+	r.notify()
+}
+
+// trim удаляет самые старые завершенные запуски сверх maxRuns
+func (r *runRegistry) trim() {
+	if len(r.runs) <= maxRuns {
+		return
+	}
+	var finished []*models.RunStatus
+	for _, run := range r.runs {
+		if run.FinishedAt != nil {
+			finished = append(finished, run)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].StartedAt.Before(finished[j].StartedAt) })
+	for _, run := range finished {
+		if len(r.runs) <= maxRuns {
+			break
+		}
+		delete(r.runs, run.RunID)
+	}
+}
+
+// copyRun возвращает копию состояния запуска, чтобы его можно было отдать без блокировки
+func copyRun(run *models.RunStatus) models.RunStatus {
+	c := *run
+	c.Files = append([]string{}, run.Files...)
+	c.Errors = append([]models.RunError(nil), run.Errors...)
+	c.GroupIDs = append([]int(nil), run.GroupIDs...)
+	c.Groups = append([]models.GroupProgress{}, run.Groups...)
+	return c
+}
+
+// GetRun возвращает состояние запуска экспорта по ID: запуски этого экземпляра — из памяти,
+// запуски других реплик — из хранилища
+func (m *Manager) GetRun(runID string) (models.RunStatus, bool) {
+	if run, _, ok := m.runs.snapshot(runID); ok {
+		return run, true
+	}
+	return m.loadRunStatus(runID)
+}
+
+// WatchRun возвращает состояние запуска и канал, который закроется при следующем изменении.
+// Для запусков этого экземпляра канал закрывается при изменении состояния запусков,
+// для запусков других реплик — через runPollInterval, после чего состояние нужно перечитать
+func (m *Manager) WatchRun(runID string) (models.RunStatus, <-chan struct{}, bool) {
+	if run, changed, ok := m.runs.snapshot(runID); ok {
+		return run, changed, true
+	}
+	run, ok := m.loadRunStatus(runID)
+	if !ok {
+		return models.RunStatus{}, nil, false
+	}
+	poll := make(chan struct{})
+	time.AfterFunc(runPollInterval, func() { close(poll) })
+	return run, poll, true
+}
+
+// GetRuns возвращает последние запуски экспорта всех реплик, новые первыми
+func (m *Manager) GetRuns() []models.RunStatus {
+	byID := make(map[string]models.RunStatus)
+	for _, run := range m.storedRuns() {
+		byID[run.RunID] = run
+	}
+	// Запуски этого экземпляра — из памяти, они свежее сохраненных
+	m.runs.mu.Lock()
+	for _, run := range m.runs.runs {
+		byID[run.RunID] = copyRun(run)
+	}
+	m.runs.mu.Unlock()
+
+	runs := make([]models.RunStatus, 0, len(byID))
+	for _, run := range byID {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > maxRuns {
+		runs = runs[:maxRuns]
+	}
+	return runs
+}
+
+// runTarget — группа, входящая в запуск экспорта
+type runTarget struct {
+	projectID int64
+	treeID    int
+	group     models.ExportGroupConfig
+}
+
+// runTargets возвращает группы запуска: все группы проекта projectID (0 — всех проектов),
+// а если заданы groupIDs — только эти группы проекта
+func (m *Manager) runTargets(projectID int64, groupIDs []int) []runTarget {
+	selected := make(map[int]bool, len(groupIDs))
+	for _, id := range groupIDs {
+		selected[id] = true
+	}
+	var targets []runTarget
+	for _, project := range m.config.Projects {
+		if projectID != 0 && project.ProjectID != projectID {
+			continue
+		}
+		for _, group := range project.Groups {
+			if len(selected) == 0 || selected[group.GroupID] {
+				targets = append(targets, runTarget{projectID: project.ProjectID, treeID: project.TreeID, group: group})
+			}
+		}
+	}
+	return targets
+}
+
+// startRun регистрирует запуск экспорта групп
+func (m *Manager) startRun(runID string, projectID int64, groupIDs []int, targets []runTarget) error {
+	now := time.Now()
+	groups := make([]models.GroupProgress, 0, len(targets))
+	for _, t := range targets {
+		groups = append(groups, models.GroupProgress{
+			ProjectID: t.projectID,
+			GroupID:   t.group.GroupID,
+			GroupName: t.group.GroupName,
+			Stage:     StageQueued,
+			Attempts:  m.config.MaxRetries,
+			UpdatedAt: now,
+		})
+	}
+	if err := m.runs.start(runID, projectID, groupIDs, groups); err != nil {
+		return err
+	}
+	go m.persistRun(runID)
+	return nil
+}
+
+// StartExport запускает параллельный экспорт проекта (0 — всех проектов) в фоне
+// и возвращает ID запуска для отслеживания через GetRun.
+// Если заданы groupIDs, выгружаются только эти группы проекта
+func (m *Manager) StartExport(projectID int64, groupIDs ...int) (string, error) {
+	if len(groupIDs) > 0 {
+		if projectID == 0 {
+			return "", fmt.Errorf("для экспорта отдельных групп укажите проект")
+		}
+		known := make(map[int]bool)
+		for _, t := range m.runTargets(projectID, nil) {
+			known[t.group.GroupID] = true
+		}
+		for _, id := range groupIDs {
+			if !known[id] {
+				return "", fmt.Errorf("группа %d не найдена в проекте %d", id, projectID)
+			}
+		}
+	}
+
+	targets := m.runTargets(projectID, groupIDs)
+	if len(targets) == 0 {
+		if projectID == 0 {
+			return "", fmt.Errorf("нет групп для экспорта")
+		}
+		return "", fmt.Errorf("проект %d не найден или не содержит групп", projectID)
+	}
+
+	runID := newRunID()
+	if err := m.startRun(runID, projectID, groupIDs, targets); err != nil {
+		return "", err
+	}
+	go m.performExportParallel(runID, projectID, targets)
+	return runID, nil
+}
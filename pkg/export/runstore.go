@@ -0,0 +1,140 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// Состояние запуска сохраняется в хранилище не чаще runPersistInterval, а запуски других реплик
+// перечитываются из хранилища раз в runPollInterval
+const (
+	runPersistInterval = 2 * time.Second
+	runPollInterval    = 2 * time.Second
+)
+
+// persistRun сохраняет состояние запуска этого экземпляра в хранилище при изменениях,
+// пока запуск не завершится. Частые изменения этапов групп объединяются
+func (m *Manager) persistRun(runID string) {
+	for {
+		run, changed, ok := m.runs.snapshot(runID)
+		if !ok {
+			return
+		}
+		m.saveRunStatus(run)
+		if run.FinishedAt != nil {
+			return
+		}
+		<-changed
+		time.Sleep(runPersistInterval)
+	}
+}
+
+// saveRunStatus записывает состояние запуска в файл testops_run_<run_id>.json
+func (m *Manager) saveRunStatus(run models.RunStatus) {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		log.Printf("Ошибка формирования состояния запуска %s: %v", run.RunID, err)
+		return
+	}
+	filename := storage.RunStatusFilename(run.RunID)
+	meta := models.ExportMetadata{
+		Filename:  filename,
+		ProjectID: run.ProjectID,
+		GroupName: storage.RunStatusGroupName,
+		RunID:     run.RunID,
+		CreatedAt: run.StartedAt,
+	}
+	if err := m.storage.SaveFile(data, filename, meta); err != nil {
+		log.Printf("Ошибка сохранения состояния запуска %s: %v", run.RunID, err)
+	}
+}
+
+// loadRunStatus читает состояние запуска другой реплики из хранилища.
+// Завершенные запуски больше не меняются и читаются один раз
+func (m *Manager) loadRunStatus(runID string) (models.RunStatus, bool) {
+	if !validRunID(runID) {
+		return models.RunStatus{}, false
+	}
+	m.runs.mu.Lock()
+	run, ok := m.runs.stored[runID]
+	m.runs.mu.Unlock()
+	if ok {
+		return run, true
+	}
+
+	data, err := m.storage.GetFile(storage.RunStatusFilename(runID))
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Ошибка чтения состояния запуска %s: %v", runID, err)
+		}
+		return models.RunStatus{}, false
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		log.Printf("Ошибка разбора состояния запуска %s: %v", runID, err)
+		return models.RunStatus{}, false
+	}
+	if run.FinishedAt != nil {
+		m.runs.mu.Lock()
+		m.runs.stored[runID] = run
+		m.runs.mu.Unlock()
+	}
+	return run, true
+}
+
+// storedRuns возвращает последние maxRuns запусков, сохраненных в хранилище
+func (m *Manager) storedRuns() []models.RunStatus {
+	files, err := m.storage.ListFiles()
+	if err != nil {
+		log.Printf("Ошибка получения списка запусков: %v", err)
+		return nil
+	}
+	var ids []string
+	for _, f := range files {
+		if storage.IsRunStatus(f.Name) {
+			ids = append(ids, storage.RunIDFromStatus(f.Name))
+		}
+	}
+	// ID запуска начинается со времени старта — новые запуски в конце
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	if len(ids) > maxRuns {
+		ids = ids[:maxRuns]
+	}
+
+	keep := make(map[string]bool, len(ids))
+	runs := make([]models.RunStatus, 0, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+		if run, ok := m.loadRunStatus(id); ok {
+			runs = append(runs, run)
+		}
+	}
+	// Запуски, вытесненные из списка или удаленные очисткой, больше не нужны в кэше
+	m.runs.mu.Lock()
+	for id := range m.runs.stored {
+		if !keep[id] {
+			delete(m.runs.stored, id)
+		}
+	}
+	m.runs.mu.Unlock()
+	return runs
+}
+
+// validRunID проверяет, что ID запуска состоит только из цифр, латинских букв и дефисов
+// и годится для имени файла
+func validRunID(runID string) bool {
+	if runID == "" {
+		return false
+	}
+	for _, c := range runID {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// scrubState хранит последний отчет проверки целостности и не дает запускать проверки параллельно.
+// Отчет сохраняется в хранилище (storage.ScrubReportFilename) и перечитывается из него не чаще
+// metadataTTL, чтобы отчет проверки, выполненной ведущим экземпляром, видели все реплики
+type scrubState struct {
+	mu       sync.Mutex
+	running  bool
+	last     *models.ScrubReport
+	loadedAt time.Time
+}
+
+// Scrub читает все файлы хранилища и проверяет их целостность: контрольную сумму из метаданных,
+// разбор CSV с ожидаемым заголовком и числом строк, ZIP архивы и манифесты.
+// Поврежденные файлы помечаются карантином в метаданных (SCRUB_QUARANTINE).
+func (m *Manager) Scrub() models.ScrubReport {
+	m.scrub.mu.Lock()
+	if m.scrub.running {
+		m.scrub.mu.Unlock()
+		log.Println("Проверка целостности уже выполняется")
+		return models.ScrubReport{Error: "проверка уже выполняется"}
+	}
+	m.scrub.running = true
+	m.scrub.mu.Unlock()
+
+	report := models.ScrubReport{StartedAt: time.Now()}
+	log.Println("🔍 Начинаем проверку целостности хранилища...")
+
+	files, err := m.listAllFiles()
+	if err != nil {
+		report.Error = fmt.Sprintf("ошибка получения списка файлов: %v", err)
+	}
+	for _, f := range files {
+		report.Checked++
+		problem := m.checkFile(f)
+		if problem == "" {
+			// Файл восстановлен — снимаем карантин
+			if f.Quarantined {
+				if err := m.quarantine(f, ""); err != nil {
+					log.Printf("Ошибка снятия карантина с файла %s: %v", f.Name, err)
+				}
+			}
+			continue
+		}
+
+		report.Corrupt++
+		log.Printf("⚠️ Поврежден файл %s: %s", f.Name, problem)
+
+		quarantined := false
+		if m.config.ScrubQuarantine && !f.Quarantined {
+			if err := m.quarantine(f, problem); err != nil {
+				log.Printf("Ошибка карантина файла %s: %v", f.Name, err)
+			} else {
+				report.Quarantined++
+				quarantined = true
+			}
+		}
+		report.Problems = append(report.Problems, models.ScrubProblem{
+			Name:        f.Name,
+			ProjectID:   f.ProjectID,
+			Problem:     problem,
+			Quarantined: quarantined,
+		})
+	}
+
+	report.FinishedAt = time.Now()
+	log.Printf("Проверка целостности завершена: проверено %d, повреждено %d", report.Checked, report.Corrupt)
+
+	m.saveScrubReport(report)
+
+	m.scrub.mu.Lock()
+	m.scrub.running = false
+	m.scrub.last = &report
+	m.scrub.loadedAt = time.Now()
+	m.scrub.mu.Unlock()
+	return report
+}
+
+// LastScrubReport возвращает отчет последней проверки целостности любой реплики
+// (nil, если проверок не было)
+func (m *Manager) LastScrubReport() *models.ScrubReport {
+	m.scrub.mu.Lock()
+	defer m.scrub.mu.Unlock()
+	if m.scrub.last != nil && time.Since(m.scrub.loadedAt) < metadataTTL {
+		return m.scrub.last
+	}
+	if report := m.loadScrubReport(); report != nil {
+		m.scrub.last = report
+	}
+	m.scrub.loadedAt = time.Now()
+	return m.scrub.last
+}
+
+// saveScrubReport сохраняет отчет проверки в хранилище
+func (m *Manager) saveScrubReport(report models.ScrubReport) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Printf("Ошибка формирования отчета проверки: %v", err)
+		return
+	}
+	meta := models.ExportMetadata{
+		Filename:  storage.ScrubReportFilename,
+		GroupName: storage.ScrubGroupName,
+		CreatedAt: report.FinishedAt,
+	}
+	if err := m.storage.SaveFile(data, storage.ScrubReportFilename, meta); err != nil {
+		log.Printf("Ошибка сохранения отчета проверки: %v", err)
+	}
+}
+
+// loadScrubReport читает отчет проверки из хранилища (nil, если отчета нет или он не читается)
+func (m *Manager) loadScrubReport() *models.ScrubReport {
+	data, err := m.storage.GetFile(storage.ScrubReportFilename)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Ошибка чтения отчета проверки: %v", err)
+		}
+		return nil
+	}
+	var report models.ScrubReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		log.Printf("Ошибка разбора отчета проверки: %v", err)
+		return nil
+	}
+	return &report
+}
+
+// checkFile проверяет один файл и возвращает описание проблемы (пусто — файл в порядке)
+func (m *Manager) checkFile(f models.ExportFile) string {
+	data, err := m.storage.GetFile(f.Name)
+	if err != nil {
+		return fmt.Sprintf("ошибка чтения: %v", err)
+	}
+
+	meta := m.lookupMetadata(f.Name)
+	if meta != nil && meta.SHA256 != "" && sha256Hex(data) != meta.SHA256 {
+		return fmt.Sprintf("контрольная сумма не совпадает (размер %d, ожидался %d)", len(data), meta.Size)
+	}
+
+	switch {
+	case f.Bundle:
+		if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return fmt.Sprintf("архив не читается: %v", err)
+		}
+	case f.Manifest:
+		var manifest models.RunManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Sprintf("манифест не читается: %v", err)
+		}
+	default:
+		return checkCSV(f.Name, data, meta)
+	}
+	return ""
+}
+
+// checkCSV проверяет, что файл экспорта распаковывается и разбирается как CSV
+// с заголовком и числом строк, записанными при сохранении
+func checkCSV(filename string, data []byte, meta *models.ExportMetadata) string {
+	csvData, err := storage.Decompress(data, storage.CompressionFromName(filename))
+	if err != nil {
+		return err.Error()
+	}
+	header, rows, err := csvStats(csvData)
+	if err != nil {
+		return fmt.Sprintf("CSV не разбирается: %v", err)
+	}
+	if len(header) == 0 {
+		return "пустой файл"
+	}
+	if meta == nil {
+		return ""
+	}
+	if len(meta.Header) > 0 && strings.Join(header, ";") != strings.Join(meta.Header, ";") {
+		return "заголовок CSV отличается от сохраненного"
+	}
+	if meta.Rows > 0 && rows != meta.Rows {
+		return fmt.Sprintf("строк %d, ожидалось %d", rows, meta.Rows)
+	}
+	return ""
+}
+
+// quarantine помечает файл как поврежденный в метаданных (пустая проблема снимает карантин)
+func (m *Manager) quarantine(f models.ExportFile, problem string) error {
+	_, err := m.updateMetadata(f, func(meta *models.ExportMetadata) bool {
+		meta.Quarantined = problem != ""
+		meta.Problem = problem
+		return true
+	})
+	return err
+}
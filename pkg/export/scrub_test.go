@@ -0,0 +1,43 @@
+package export
+
+import (
+	"testing"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+func TestCheckCSV(t *testing.T) {
+	csvData := []byte("\xef\xbb\xbfID;Name;Сценарий\n1;Вход;\"шаг 1\nшаг 2\"\n2;Выход;\n")
+	gz, err := storage.Compress(csvData, storage.CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := []string{"ID", "Name", "Сценарий"}
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		meta     *models.ExportMetadata
+		wantOK   bool
+	}{
+		{"совпадает с метаданными", "a.csv", csvData, &models.ExportMetadata{Header: header, Rows: 2}, true},
+		{"без метаданных", "a.csv", csvData, nil, true},
+		{"метаданные без заголовка и строк", "a.csv", csvData, &models.ExportMetadata{}, true},
+		{"сжатый файл", "a.csv.gz", gz, &models.ExportMetadata{Header: header, Rows: 2}, true},
+		{"пустой файл", "a.csv", nil, nil, false},
+		{"другой заголовок", "a.csv", csvData, &models.ExportMetadata{Header: []string{"ID", "Name"}, Rows: 2}, false},
+		{"другое число строк", "a.csv", csvData, &models.ExportMetadata{Header: header, Rows: 3}, false},
+		{"сжатие не распаковывается", "a.csv.gz", csvData, nil, false},
+		{"zstd вместо gzip", "a.csv.zst", gz, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := checkCSV(tt.filename, tt.data, tt.meta)
+			if (problem == "") != tt.wantOK {
+				t.Errorf("checkCSV = %q, файл в порядке ожидался: %v", problem, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package export
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// snapshotLabel приводит метку снимка к безопасному виду. Метка не может содержать «__»,
+// так как это разделитель метки и исходного имени в именах копий.
+func snapshotLabel(label string) (string, error) {
+	if strings.TrimSpace(label) == "" {
+		return "", fmt.Errorf("метка снимка не указана")
+	}
+	safe := storage.SanitizeName(label)
+	for strings.Contains(safe, "__") {
+		safe = strings.ReplaceAll(safe, "__", "_")
+	}
+	return safe, nil
+}
+
+// CreateSnapshot создает снимок релиза: копирует выбранные файлы под префиксом снимка.
+// Если файлы не указаны, в снимок попадает последний экспорт каждой группы каждого проекта.
+// Копии закреплены (не удаляются автоочисткой) и не могут быть удалены или перезаписаны.
+// Если скопировать не удалось хотя бы один файл, уже сделанные копии удаляются, чтобы не занимать метку.
+func (m *Manager) CreateSnapshot(label string, filenames []string) (*models.Snapshot, error) {
+	label, err := snapshotLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	allFiles, err := m.listAllFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range allFiles {
+		if f.Snapshot == label {
+			return nil, fmt.Errorf("снимок %s уже существует", label)
+		}
+	}
+
+	sources, err := snapshotSources(allFiles, filenames)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("нет файлов для снимка")
+	}
+
+	now := time.Now()
+	var copies []string
+	for _, f := range sources {
+		data, err := m.storage.GetFile(f.Name)
+		if err != nil {
+			m.removeSnapshotCopies(label, copies)
+			return nil, fmt.Errorf("ошибка чтения файла %s: %v", f.Name, err)
+		}
+
+		meta := models.ExportMetadata{
+			ProjectID:   f.ProjectID,
+			GroupID:     f.GroupID,
+			GroupName:   f.GroupName,
+			RunID:       f.RunID,
+			Compression: f.Compression,
+			Rows:        f.Rows,
+		}
+		if existing := m.lookupMetadata(f.Name); existing != nil {
+			meta = *existing
+		}
+		meta.Filename = storage.SnapshotFilename(label, f.Name)
+		meta.CreatedAt = now
+		meta.Pinned = true
+		meta.Snapshot = label
+		meta.Source = f.Name
+
+		if err := m.storeFile(data, meta); err != nil {
+			// Метаданные записываются первыми и могли остаться без копии
+			m.removeMetadata(meta.Filename)
+			m.removeSnapshotCopies(label, copies)
+			return nil, fmt.Errorf("ошибка сохранения копии %s в снимок: %v", f.Name, err)
+		}
+		copies = append(copies, meta.Filename)
+	}
+	log.Printf("📸 Создан снимок %s, файлов: %d", label, len(sources))
+
+	return m.GetSnapshot(label)
+}
+
+// removeSnapshotCopies удаляет копии недосозданного снимка вместе с метаданными.
+// Копии защищены от DeleteExportFile, поэтому удаляются напрямую из хранилища
+func (m *Manager) removeSnapshotCopies(label string, copies []string) {
+	for _, name := range copies {
+		if err := m.storage.DeleteFile(name); err != nil {
+			log.Printf("❌ Ошибка удаления копии %s недосозданного снимка %s: %v", name, label, err)
+		}
+		m.removeMetadata(name)
+	}
+	if len(copies) > 0 {
+		log.Printf("Снимок %s не создан, удалено копий: %d", label, len(copies))
+	}
+}
+
+// snapshotSources выбирает файлы для снимка: указанные явно или последний экспорт каждой группы
+func snapshotSources(allFiles []models.ExportFile, filenames []string) ([]models.ExportFile, error) {
+	if len(filenames) > 0 {
+		byName := make(map[string]models.ExportFile, len(allFiles))
+		for _, f := range allFiles {
+			byName[f.Name] = f
+		}
+		var sources []models.ExportFile
+		for _, name := range filenames {
+			f, ok := byName[name]
+			if !ok || f.Snapshot != "" {
+				return nil, fmt.Errorf("файл не найден: %s", name)
+			}
+			sources = append(sources, f)
+		}
+		return sources, nil
+	}
+
+	// Файлы отсортированы от новых к старым — берем первый файл каждой группы
+	seen := make(map[string]bool)
+	var sources []models.ExportFile
+	for _, f := range allFiles {
+		if f.Snapshot != "" || f.Bundle || f.Manifest || f.Quarantined {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d/%s", f.ProjectID, f.GroupID, f.GroupName)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sources = append(sources, f)
+	}
+	return sources, nil
+}
+
+// GetSnapshots возвращает снимки релизов (новые сверху)
+func (m *Manager) GetSnapshots() ([]models.Snapshot, error) {
+	allFiles, err := m.listAllFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	byLabel := make(map[string]*models.Snapshot)
+	var snapshots []*models.Snapshot
+	sizes := make(map[string]int64)
+	for _, f := range allFiles {
+		if f.Snapshot == "" {
+			continue
+		}
+		snap, ok := byLabel[f.Snapshot]
+		if !ok {
+			snap = &models.Snapshot{Label: f.Snapshot}
+			byLabel[f.Snapshot] = snap
+			snapshots = append(snapshots, snap)
+		}
+		snap.Files = append(snap.Files, f)
+		sizes[f.Snapshot] += f.Size
+		if f.ModifiedTime.After(snap.CreatedAt) {
+			snap.CreatedAt = f.ModifiedTime
+		}
+	}
+
+	result := make([]models.Snapshot, 0, len(snapshots))
+	for _, snap := range snapshots {
+		snap.FormattedDate = snap.CreatedAt.Format("02.01.2006 15:04:05")
+		snap.FormattedSize = m.FormatFileSize(sizes[snap.Label])
+		result = append(result, *snap)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// GetSnapshot возвращает снимок по метке
+func (m *Manager) GetSnapshot(label string) (*models.Snapshot, error) {
+	snapshots, err := m.GetSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snapshots {
+		if snap.Label == label {
+			return &snap, nil
+		}
+	}
+	return nil, fmt.Errorf("снимок %s не найден", label)
+}
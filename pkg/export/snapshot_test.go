@@ -0,0 +1,69 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// failingStorage не сохраняет файлы, имя которых содержит fail
+type failingStorage struct {
+	storage.Storage
+	fail string
+}
+
+func (s *failingStorage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
+	if s.fail != "" && strings.Contains(filename, s.fail) && !storage.IsMetaSidecar(filename) {
+		return errors.New("хранилище недоступно")
+	}
+	return s.Storage.SaveFile(data, filename, meta)
+}
+
+func TestCreateSnapshotRollsBackOnError(t *testing.T) {
+	m := NewManager(&config.Config{ExportPath: t.TempDir()})
+	names := []string{
+		"testops_export_17_API_2025-07-15_12-02-56.csv",
+		"testops_export_17_UI_2025-07-15_12-02-56.csv",
+		"testops_export_17_Web_2025-07-15_12-02-56.csv",
+	}
+	for _, name := range names {
+		if err := m.storeFile([]byte("ID;Name\n1;Вход\n"), models.ExportMetadata{Filename: name, ProjectID: 17}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failing := &failingStorage{Storage: m.storage, fail: "__" + names[2]}
+	m.storage = failing
+	if _, err := m.CreateSnapshot("v1.0", names); err == nil {
+		t.Fatal("снимок создан, хотя копия не сохранилась")
+	}
+
+	files, err := m.listAllFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if storage.IsSnapshotFile(f.Name) {
+			t.Errorf("осталась копия недосозданного снимка: %s", f.Name)
+		}
+	}
+	for _, name := range names {
+		if _, err := m.storage.GetFile(storage.MetaSidecarName(storage.SnapshotFilename("v1.0", name))); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("остались метаданные копии %s: %v", name, err)
+		}
+	}
+
+	// Метка свободна: после восстановления хранилища снимок создается
+	failing.fail = ""
+	snapshot, err := m.CreateSnapshot("v1.0", names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Files) != len(names) {
+		t.Errorf("в снимке %d файлов, ожидалось %d", len(snapshot.Files), len(names))
+	}
+}
@@ -16,17 +16,20 @@ type ExportRequest struct {
 		Inverted         bool  `json:"inverted"`
 		Deleted          bool  `json:"deleted"`
 	} `json:"selection"`
-	Mapping []struct {
-		Field          string `json:"field"`
-		Name           string `json:"name"`
-		ItemsSeparator string `json:"itemsSeparator,omitempty"`
-		IntegrationID  int    `json:"integrationId,omitempty"`
-		RoleID         int    `json:"roleId,omitempty"`
-		CustomFieldID  int    `json:"customFieldId,omitempty"`
-	} `json:"mapping"`
-	ColumnSeparator string `json:"columnSeparator"`
-	IncludeHeaders  bool   `json:"includeHeaders"`
-	Name            string `json:"name"`
+	Mapping         []ExportMapping `json:"mapping"`
+	ColumnSeparator string          `json:"columnSeparator"`
+	IncludeHeaders  bool            `json:"includeHeaders"`
+	Name            string          `json:"name"`
+}
+
+// ExportMapping описывает колонку CSV, запрашиваемую в экспорте
+type ExportMapping struct {
+	Field          string `json:"field"`
+	Name           string `json:"name"`
+	ItemsSeparator string `json:"itemsSeparator,omitempty"`
+	IntegrationID  int    `json:"integrationId,omitempty"`
+	RoleID         int    `json:"roleId,omitempty"`
+	CustomFieldID  int    `json:"customFieldId,omitempty"`
 }
 
 // ExportResponse представляет ответ на запрос экспорта
@@ -41,6 +44,28 @@ type ExportConfig struct {
 	ProjectID int64 // ID проекта TestOps
 }
 
+// ExportMetadata описывает сохраненный файл. Передается в хранилище при сохранении
+// и хранится рядом с файлом в <имя>.meta.json (индекс метаданных)
+type ExportMetadata struct {
+	Filename    string    `json:"filename"`
+	ProjectID   int64     `json:"project_id"` // ID проекта TestOps
+	GroupID     int       `json:"group_id,omitempty"`
+	GroupName   string    `json:"group_name"`
+	RunID       string    `json:"run_id,omitempty"` // ID запуска экспорта, общий для всех групп запуска
+	CreatedAt   time.Time `json:"created_at"`       // Время сохранения экспорта
+	Compression string    `json:"compression,omitempty"`
+	ExportID    int       `json:"export_id,omitempty"` // ID экспорта в TestOps
+	Size        int64     `json:"size"`                // Размер файла в байтах (как хранится)
+	SHA256      string    `json:"sha256"`
+	Rows        int       `json:"rows,omitempty"`     // Количество строк CSV без заголовка
+	Pinned      bool      `json:"pinned,omitempty"`   // Закреплен: не удаляется автоочисткой
+	Snapshot    string    `json:"snapshot,omitempty"` // Метка снимка релиза, в который скопирован файл
+	Source      string    `json:"source,omitempty"`   // Исходный файл копии в снимке
+	Header      []string  `json:"header,omitempty"`   // Колонки CSV на момент сохранения
+	Quarantined bool      `json:"quarantined,omitempty"`
+	Problem     string    `json:"problem,omitempty"` // Причина карантина (проверка целостности)
+}
+
 // ExportFile представляет файл экспорта
 type ExportFile struct {
 	Name          string
@@ -49,6 +74,149 @@ type ExportFile struct {
 	FormattedSize string
 	FormattedDate string
 	ProjectID     int64 // ID проекта TestOps
+	GroupID       int
+	GroupName     string
+	RunID         string // ID запуска (из индекса метаданных)
+	SHA256        string // Контрольная сумма (из индекса метаданных)
+	Rows          int    // Количество строк CSV (из индекса метаданных)
+	Compression   string // Кодек сжатия: "", "gzip" или "zstd"
+	Bundle        bool   // ZIP архив всех групп запуска
+	Manifest      bool   // Манифест запуска
+	Pinned        bool   // Закреплен: не удаляется автоочисткой
+	Snapshot      string // Метка снимка релиза (для копий в снимке)
+	Quarantined   bool   // Поврежден (найден проверкой целостности)
+	Problem       string // Причина карантина
+	MetaVersion   string // Версия файла метаданных из списка хранилища (пусто — метаданных нет)
+}
+
+// Snapshot описывает снимок релиза — неизменяемые копии набора файлов экспорта
+type Snapshot struct {
+	Label         string
+	CreatedAt     time.Time
+	FormattedDate string
+	FormattedSize string
+	Files         []ExportFile
+}
+
+// RunManifest описывает файлы, созданные запуском экспорта.
+// Сохраняется отдельным файлом запуска и как manifest.json внутри ZIP архивов.
+type RunManifest struct {
+	RunID     string          `json:"run_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	ProjectID int64           `json:"project_id,omitempty"` // 0 — все проекты
+	Mapping   []ExportMapping `json:"mapping,omitempty"`    // Колонки, запрошенные в TestOps
+	Files     []ManifestFile  `json:"files"`
+}
+
+// ManifestFile описывает файл в манифесте
+type ManifestFile struct {
+	Name        string   `json:"name"`
+	ProjectID   int64    `json:"project_id"`
+	GroupID     int      `json:"group_id,omitempty"`
+	GroupName   string   `json:"group_name"`
+	ExportID    int      `json:"export_id,omitempty"` // ID экспорта в TestOps
+	Size        int64    `json:"size"`                // Размер файла в байтах (как хранится)
+	SHA256      string   `json:"sha256"`              // Контрольная сумма файла (как хранится)
+	Compression string   `json:"compression,omitempty"`
+	Rows        int      `json:"rows,omitempty"`   // Количество строк CSV без заголовка
+	Header      []string `json:"header,omitempty"` // Колонки CSV
+}
+
+// VerifyReport содержит результат проверки файлов запуска по манифесту
+type VerifyReport struct {
+	Manifest  string         `json:"manifest"`
+	RunID     string         `json:"run_id"`
+	CheckedAt time.Time      `json:"checked_at"`
+	OK        bool           `json:"ok"`
+	Files     []VerifyResult `json:"files"`
+}
+
+// VerifyResult содержит результат проверки одного файла
+type VerifyResult struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"` // ok, mismatch, missing
+	ExpectedSHA256 string `json:"expected_sha256"`
+	ActualSHA256   string `json:"actual_sha256,omitempty"`
+	ExpectedSize   int64  `json:"expected_size"`
+	ActualSize     int64  `json:"actual_size,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RunStatus описывает состояние запуска экспорта
+type RunStatus struct {
+	RunID      string          `json:"run_id"`
+	ProjectID  int64           `json:"project_id,omitempty"` // 0 — все проекты
+	GroupIDs   []int           `json:"group_ids,omitempty"`  // Отдельные группы проекта, пусто — все группы
+	Status     string          `json:"status"`               // running, success, partial, failed
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Total      int             `json:"total"` // Количество групп в запуске
+	Succeeded  int             `json:"succeeded"`
+	Failed     int             `json:"failed"`
+	Files      []string        `json:"files"` // Сохраненные файлы экспорта
+	Errors     []RunError      `json:"errors,omitempty"`
+	Groups     []GroupProgress `json:"groups"` // Ход экспорта по группам
+}
+
+// GroupProgress описывает ход экспорта группы в запуске
+type GroupProgress struct {
+	ProjectID int64     `json:"project_id"`
+	GroupID   int       `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	Stage     string    `json:"stage"`   // queued, requesting, waiting, downloading, saved, retry, failed
+	Attempt   int       `json:"attempt"` // Текущая попытка, для retry — следующая
+	Attempts  int       `json:"attempts"`
+	File      string    `json:"file,omitempty"`
+	Error     string    `json:"error,omitempty"` // Последняя ошибка (retry, failed)
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RunError описывает группу, экспорт которой не удался
+type RunError struct {
+	ProjectID int64  `json:"project_id"`
+	GroupID   int    `json:"group_id"`
+	GroupName string `json:"group_name"`
+	Error     string `json:"error"`
+}
+
+// GroupOverview описывает группу экспорта из конфигурации и ее последний экспорт
+type GroupOverview struct {
+	ProjectID  int64
+	GroupID    int
+	GroupName  string
+	LastFile   *ExportFile // nil — экспортов группы еще нет
+	Age        string      // Сколько прошло с последнего экспорта
+	Stale      bool        // Последний экспорт старше STALE_AFTER или экспортов нет
+	LastRunID  string
+	LastRun    *GroupProgress // Ход группы в последнем запуске; nil — не выгружалась с запуска сервиса
+	LatestURL  string         // Постоянный адрес последнего экспорта (/latest/...)
+	CanTrigger bool           // Пользователь может запустить экспорт группы
+}
+
+// GroupsPage представляет данные страницы групп
+type GroupsPage struct {
+	Groups            []GroupOverview
+	Projects          []ProjectInfo
+	SelectedProjectID int64
+	StaleAfter        string // Порог устаревания, пусто — выключен
+	StaleCount        int
+	FailedCount       int // Группы, последний запуск которых завершился ошибкой
+}
+
+// AuditRecord описывает действие пользователя в журнале аудита
+type AuditRecord struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user"`             // Пусто — аутентификация выключена
+	Method       string    `json:"method,omitempty"` // Способ входа пользователя
+	RemoteAddr   string    `json:"remote_addr,omitempty"`
+	ForwardedFor string    `json:"forwarded_for,omitempty"` // X-Forwarded-For, только от доверенного прокси (TRUSTED_PROXIES)
+	Action       string    `json:"action"`                  // delete
+	File         string    `json:"file"`
+	ProjectID    int64     `json:"project_id"`
+	Size         int64     `json:"size,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
+	Result       string    `json:"result"` // ok, denied, error
+	Error        string    `json:"error,omitempty"`
 }
 
 // ExportGroupConfig описывает группу для экспорта в рамках проекта
@@ -59,9 +227,20 @@ type ExportGroupConfig struct {
 
 // ProjectConfig описывает проект TestOps и его группы
 type ProjectConfig struct {
-	ProjectID int64               `json:"project_id"`
-	TreeID    int                 `json:"tree_id"`
-	Groups    []ExportGroupConfig `json:"groups"`
+	ProjectID  int64               `json:"project_id"`
+	TreeID     int                 `json:"tree_id"`
+	Groups     []ExportGroupConfig `json:"groups"`
+	Quota      string              `json:"quota,omitempty"` // Квота проекта ("200Mi"), перекрывает STORAGE_PROJECT_QUOTA
+	QuotaBytes int64               `json:"-"`
+}
+
+// AccessRule выдает пользователям и группам права на проекты (файл ACCESS_CONFIG)
+type AccessRule struct {
+	Users       []string `json:"users,omitempty"`  // Имена пользователей, email или имена клиентов AUTH_TOKENS
+	Groups      []string `json:"groups,omitempty"` // Группы пользователя из OIDC
+	Projects    []int64  `json:"projects,omitempty"`
+	AllProjects bool     `json:"all_projects,omitempty"` // Все проекты, в том числе общие архивы и манифесты
+	Actions     []string `json:"actions"`                // view, download, trigger, delete, admin
 }
 
 // ProjectInfo содержит информацию о проекте для UI
@@ -78,9 +257,87 @@ type NextExportInfo struct {
 	ErrorMessage     string
 }
 
+// ScrubReport содержит результат проверки целостности хранилища
+type ScrubReport struct {
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Checked     int
+	Corrupt     int
+	Quarantined int
+	Problems    []ScrubProblem
+	Error       string // Ошибка, из-за которой проверка не выполнилась
+}
+
+// ScrubProblem описывает поврежденный файл
+type ScrubProblem struct {
+	Name        string
+	ProjectID   int64
+	Problem     string
+	Quarantined bool // Файл помечен карантином при этой проверке
+}
+
+// StorageUsage содержит занятое место и квоту хранилища для UI
+type StorageUsage struct {
+	Used           int64
+	Quota          int64 // 0 — без ограничения
+	FormattedUsed  string
+	FormattedQuota string
+	Percent        int
+}
+
+// User описывает аутентифицированного пользователя или клиента API
+type User struct {
+	Name   string   `json:"name"`
+	Email  string   `json:"email,omitempty"`
+	Groups []string `json:"groups,omitempty"` // Группы из OIDC (claim OIDC_GROUPS_CLAIM)
+	Method string   `json:"method"`           // Способ входа: token, basic, oidc
+}
+
+// FileList описывает фильтры, сортировку и страницу списка файлов на главной странице
+type FileList struct {
+	Group     string
+	Search    string
+	Since     string
+	Until     string
+	Sort      string // date, size, name
+	Order     string // asc, desc
+	Page      int
+	Pages     int
+	Total     int               // Файлов по условиям отбора
+	Filtered  bool              // Задано хотя бы одно условие отбора
+	Groups    []string          // Группы для фильтра
+	PrevURL   string            // Пусто на первой странице
+	NextURL   string            // Пусто на последней странице
+	SortURLs  map[string]string // Ссылки сортировки по колонкам
+	SortMarks map[string]string // Стрелка у колонки текущей сортировки
+}
+
+// PreviewPage представляет данные страницы просмотра CSV экспорта
+type PreviewPage struct {
+	Filename  string
+	Columns   []PreviewColumn // Все колонки файла
+	Header    []string        // Выбранные колонки
+	Rows      [][]string      // Строки текущей страницы, только выбранные колонки
+	Search    string
+	SearchIn  string // Колонка поиска, пусто — все колонки
+	Page      int
+	Pages     int
+	Total     int // Строк по условию поиска
+	TotalRows int // Строк в файле
+	PrevURL   string
+	NextURL   string
+}
+
+// PreviewColumn описывает колонку CSV на странице просмотра
+type PreviewColumn struct {
+	Name     string
+	Selected bool
+}
+
 // PageData представляет данные для веб-страницы
 type PageData struct {
-	Files             []ExportFile
+	Files             []ExportFile // Файлы текущей страницы списка
+	List              FileList
 	TotalFiles        string
 	TotalSize         string
 	LastExport        string
@@ -88,4 +345,8 @@ type PageData struct {
 	SelectedProjectID int64
 	CronSchedule      string         // Расписание cron из конфига
 	NextExport        NextExportInfo // Информация о следующем экспорте
+	Usage             StorageUsage   // Занятое место и квота (общая или выбранного проекта)
+	Scrub             *ScrubReport   // Последняя проверка целостности (nil — не выполнялась)
+	User              *User          // Вошедший пользователь (nil — аутентификация выключена)
+	CanTrigger        bool           // Пользователь может запустить экспорт выбранного проекта
 }
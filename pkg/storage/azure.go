@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// azurePrefix — префикс блобов экспорта в контейнере
+const azurePrefix = "exports/"
+
+// AzureStorage представляет хранилище Azure Blob Storage
+type AzureStorage struct {
+	client    *azblob.Client
+	container string
+}
+
+// NewAzureStorage создает новый экземпляр Azure Blob хранилища.
+// Поддерживаются строка подключения (в том числе для Azurite),
+// ключ аккаунта и SAS токен.
+func NewAzureStorage(cfg *config.Config) (*AzureStorage, error) {
+	if !cfg.AzureEnabled {
+		return nil, fmt.Errorf("Azure Blob не включен в конфигурации")
+	}
+
+	serviceURL := cfg.AzureEndpoint
+	if serviceURL == "" && cfg.AzureAccountName != "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.AzureAccountName)
+	}
+
+	var client *azblob.Client
+	var err error
+
+	switch {
+	case cfg.AzureConnectionString != "":
+		client, err = azblob.NewClientFromConnectionString(cfg.AzureConnectionString, nil)
+	case cfg.AzureAccountKey != "":
+		var cred *azblob.SharedKeyCredential
+		cred, err = azblob.NewSharedKeyCredential(cfg.AzureAccountName, cfg.AzureAccountKey)
+		if err == nil {
+			client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+		}
+	default:
+		client, err = azblob.NewClientWithNoCredential(strings.TrimRight(serviceURL, "/")+"/?"+strings.TrimPrefix(cfg.AzureSASToken, "?"), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания клиента Azure Blob: %v", err)
+	}
+
+	// Проверяем доступность контейнера, при необходимости создаем его
+	_, err = client.CreateContainer(context.TODO(), cfg.AzureContainer, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists, bloberror.AuthorizationPermissionMismatch) {
+		return nil, fmt.Errorf("ошибка доступа к контейнеру Azure %s: %v", cfg.AzureContainer, err)
+	}
+
+	log.Printf("✅ Azure Blob хранилище инициализировано: контейнер %s", cfg.AzureContainer)
+
+	return &AzureStorage{
+		client:    client,
+		container: cfg.AzureContainer,
+	}, nil
+}
+
+// SaveFile сохраняет файл в Azure Blob
+func (s *AzureStorage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
+	key := azurePrefix + filename
+
+	// Имена метаданных Azure должны быть идентификаторами, а значения — ASCII
+	metadata := map[string]*string{
+		"upload_time": to.Ptr(time.Now().Format(time.RFC3339)),
+		"project_id":  to.Ptr(strconv.FormatInt(meta.ProjectID, 10)),
+		"group_id":    to.Ptr(strconv.Itoa(meta.GroupID)),
+		"group_name":  to.Ptr(url.QueryEscape(meta.GroupName)),
+	}
+	if meta.Compression != "" {
+		metadata["compression"] = to.Ptr(meta.Compression)
+	}
+
+	_, err := s.client.UploadBuffer(context.TODO(), s.container, key, data, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: to.Ptr(ContentType(filename))},
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки файла в Azure Blob: %v", err)
+	}
+
+	log.Printf("✅ Файл сохранен в Azure Blob: %s", key)
+	return nil
+}
+
+// GetFile возвращает содержимое файла из Azure Blob
+func (s *AzureStorage) GetFile(filename string) ([]byte, error) {
+	resp, err := s.client.DownloadStream(context.TODO(), s.container, azurePrefix+filename, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения файла из Azure Blob: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения содержимого файла: %v", err)
+	}
+
+	return data, nil
+}
+
+// ListFiles возвращает список файлов из Azure Blob
+func (s *AzureStorage) ListFiles() ([]models.ExportFile, error) {
+	var files []models.ExportFile
+	versions := make(metaVersions)
+
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix:  to.Ptr(azurePrefix),
+		Include: azblob.ListBlobsInclude{Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка файлов из Azure Blob: %v", err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil || item.Properties == nil {
+				continue
+			}
+			if IsMetaSidecar(*item.Name) {
+				var size int64
+				if item.Properties.ContentLength != nil {
+					size = *item.Properties.ContentLength
+				}
+				var modified time.Time
+				if item.Properties.LastModified != nil {
+					modified = *item.Properties.LastModified
+				}
+				versions.add(path.Base(*item.Name), modified, size)
+				continue
+			}
+			if !IsExportFile(*item.Name) {
+				continue
+			}
+
+			filename := path.Base(*item.Name)
+			projectIDMeta := lookupMetadata(item.Metadata, "project_id")
+			groupNameMeta, _ := url.QueryUnescape(lookupMetadata(item.Metadata, "group_name"))
+			projectID, groupName := metadataOrFilename(filename, projectIDMeta, groupNameMeta)
+
+			var size int64
+			if item.Properties.ContentLength != nil {
+				size = *item.Properties.ContentLength
+			}
+			var modified time.Time
+			if item.Properties.LastModified != nil {
+				modified = *item.Properties.LastModified
+			}
+
+			files = append(files, models.ExportFile{
+				Name:          filename,
+				Size:          size,
+				ModifiedTime:  modified,
+				FormattedSize: formatFileSize(size),
+				FormattedDate: modified.Format("02.01.2006 15:04:05"),
+				ProjectID:     projectID,
+				GroupName:     groupName,
+				Compression:   CompressionFromName(filename),
+			})
+		}
+	}
+
+	versions.apply(files)
+	sortExportFiles(files)
+
+	return files, nil
+}
+
+// DeleteFile удаляет файл из Azure Blob
+func (s *AzureStorage) DeleteFile(filename string) error {
+	key := azurePrefix + filename
+	if _, err := s.client.DeleteBlob(context.TODO(), s.container, key, nil); err != nil {
+		return fmt.Errorf("ошибка удаления файла из Azure Blob: %v", err)
+	}
+
+	log.Printf("✅ Файл удален из Azure Blob: %s", key)
+	return nil
+}
+
+// CleanupOldFiles удаляет файлы старше месяца из Azure Blob, кроме закрепленных
+func (s *AzureStorage) CleanupOldFiles(keep func(filename string) bool) error {
+	files, err := s.ListFiles()
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка файлов для очистки: %v", err)
+	}
+
+	monthAgo := time.Now().AddDate(0, -1, 0)
+	deletedCount := 0
+
+	for _, file := range files {
+		if file.ModifiedTime.Before(monthAgo) && !keep(file.Name) {
+			if err := s.DeleteFile(file.Name); err != nil {
+				log.Printf("Ошибка удаления старого файла %s: %v", file.Name, err)
+			} else {
+				deletedCount++
+			}
+		}
+	}
+
+	if deletedCount > 0 {
+		log.Printf("Удалено %d старых файлов из Azure Blob", deletedCount)
+	}
+
+	return nil
+}
+
+// lookupMetadata ищет значение метаданных без учета регистра имени
+// (Azure может вернуть имена в другом регистре)
+func lookupMetadata(metadata map[string]*string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) && v != nil {
+			return *v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Поддерживаемые кодеки сжатия экспортов
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressionExts сопоставляет кодек и расширение файла
+var compressionExts = map[string]string{
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
+// CompressionExt возвращает расширение файла для кодека (".gz", ".zst" или "")
+func CompressionExt(codec string) string {
+	return compressionExts[codec]
+}
+
+// CompressionFromName определяет кодек по расширению имени файла
+func CompressionFromName(filename string) string {
+	for codec, ext := range compressionExts {
+		if strings.HasSuffix(filename, ".csv"+ext) {
+			return codec
+		}
+	}
+	return CompressionNone
+}
+
+// IsExportFile проверяет, что имя относится к файлу экспорта (сжатому, несжатому,
+// ZIP архиву, манифесту или служебному файлу)
+func IsExportFile(filename string) bool {
+	return strings.HasSuffix(filename, ".csv") || CompressionFromName(filename) != CompressionNone ||
+		IsBundle(filename) || IsManifest(filename) || IsServiceFile(filename)
+}
+
+// UncompressedName возвращает имя файла без расширения сжатия
+func UncompressedName(filename string) string {
+	return strings.TrimSuffix(filename, CompressionExt(CompressionFromName(filename)))
+}
+
+// ContentType возвращает MIME тип файла экспорта
+func ContentType(filename string) string {
+	switch CompressionFromName(filename) {
+	case CompressionGzip:
+		return "application/gzip"
+	case CompressionZstd:
+		return "application/zstd"
+	}
+	if IsBundle(filename) {
+		return "application/zip"
+	}
+	if IsManifest(filename) {
+		return "application/json"
+	}
+	return "text/csv"
+}
+
+// Compress сжимает данные выбранным кодеком
+func Compress(data []byte, codec string) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+
+	switch codec {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		w, err = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	case CompressionZstd:
+		w, err = zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	default:
+		return nil, fmt.Errorf("неизвестный кодек сжатия: %s", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания кодека %s: %v", codec, err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("ошибка сжатия %s: %v", codec, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка сжатия %s: %v", codec, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress распаковывает данные выбранным кодеком
+func Decompress(data []byte, codec string) ([]byte, error) {
+	switch codec {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки gzip: %v", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки zstd: %v", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("неизвестный кодек сжатия: %s", codec)
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// gcsPrefix — префикс объектов экспорта в бакете
+const gcsPrefix = "exports/"
+
+// GCSStorage представляет хранилище Google Cloud Storage
+type GCSStorage struct {
+	client *gcs.Client
+	bucket *gcs.BucketHandle
+}
+
+// NewGCSStorage создает новый экземпляр GCS хранилища.
+// Без GCS_CREDENTIALS_FILE используются Application Default Credentials,
+// с GCS_ENDPOINT — эмулятор (например, fake-gcs-server) без авторизации.
+func NewGCSStorage(cfg *config.Config) (*GCSStorage, error) {
+	if !cfg.GCSEnabled {
+		return nil, fmt.Errorf("GCS не включен в конфигурации")
+	}
+
+	ctx := context.TODO()
+
+	var opts []option.ClientOption
+	if cfg.GCSEndpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.GCSEndpoint), option.WithoutAuthentication())
+	} else if cfg.GCSCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.GCSCredentialsFile))
+	}
+
+	client, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания клиента GCS: %v", err)
+	}
+
+	bucket := client.Bucket(cfg.GCSBucket)
+
+	// Проверяем доступность бакета
+	if _, err := bucket.Attrs(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ошибка доступа к GCS бакету %s: %v", cfg.GCSBucket, err)
+	}
+
+	log.Printf("✅ GCS хранилище инициализировано: бакет %s", cfg.GCSBucket)
+
+	return &GCSStorage{
+		client: client,
+		bucket: bucket,
+	}, nil
+}
+
+// SaveFile сохраняет файл в GCS
+func (s *GCSStorage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
+	ctx := context.TODO()
+	key := gcsPrefix + filename
+
+	w := s.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = ContentType(filename)
+	w.Metadata = map[string]string{
+		"original-filename": filename,
+		"upload-time":       time.Now().Format(time.RFC3339),
+		"project-id":        strconv.FormatInt(meta.ProjectID, 10),
+		"group-id":          strconv.Itoa(meta.GroupID),
+		"group-name":        meta.GroupName,
+		"compression":       meta.Compression,
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("ошибка загрузки файла в GCS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("ошибка загрузки файла в GCS: %v", err)
+	}
+
+	log.Printf("✅ Файл сохранен в GCS: %s", key)
+	return nil
+}
+
+// GetFile возвращает содержимое файла из GCS
+func (s *GCSStorage) GetFile(filename string) ([]byte, error) {
+	r, err := s.bucket.Object(gcsPrefix + filename).NewReader(context.TODO())
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения файла из GCS: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения содержимого файла: %v", err)
+	}
+
+	return data, nil
+}
+
+// ListFiles возвращает список файлов из GCS
+func (s *GCSStorage) ListFiles() ([]models.ExportFile, error) {
+	var files []models.ExportFile
+	versions := make(metaVersions)
+
+	it := s.bucket.Objects(context.TODO(), &gcs.Query{Prefix: gcsPrefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка файлов из GCS: %v", err)
+		}
+
+		if IsMetaSidecar(attrs.Name) {
+			versions.add(path.Base(attrs.Name), attrs.Updated, attrs.Size)
+			continue
+		}
+		if !IsExportFile(attrs.Name) {
+			continue
+		}
+
+		filename := path.Base(attrs.Name)
+		projectID, groupName := metadataOrFilename(filename, attrs.Metadata["project-id"], attrs.Metadata["group-name"])
+
+		files = append(files, models.ExportFile{
+			Name:          filename,
+			Size:          attrs.Size,
+			ModifiedTime:  attrs.Updated,
+			FormattedSize: formatFileSize(attrs.Size),
+			FormattedDate: attrs.Updated.Format("02.01.2006 15:04:05"),
+			ProjectID:     projectID,
+			GroupName:     groupName,
+			Compression:   CompressionFromName(filename),
+		})
+	}
+
+	versions.apply(files)
+	sortExportFiles(files)
+
+	return files, nil
+}
+
+// DeleteFile удаляет файл из GCS
+func (s *GCSStorage) DeleteFile(filename string) error {
+	key := gcsPrefix + filename
+	if err := s.bucket.Object(key).Delete(context.TODO()); err != nil {
+		return fmt.Errorf("ошибка удаления файла из GCS: %v", err)
+	}
+
+	log.Printf("✅ Файл удален из GCS: %s", key)
+	return nil
+}
+
+// CleanupOldFiles удаляет файлы старше месяца из GCS, кроме закрепленных
+func (s *GCSStorage) CleanupOldFiles(keep func(filename string) bool) error {
+	files, err := s.ListFiles()
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка файлов для очистки: %v", err)
+	}
+
+	monthAgo := time.Now().AddDate(0, -1, 0)
+	deletedCount := 0
+
+	for _, file := range files {
+		if file.ModifiedTime.Before(monthAgo) && !keep(file.Name) {
+			if err := s.DeleteFile(file.Name); err != nil {
+				log.Printf("Ошибка удаления старого файла %s: %v", file.Name, err)
+			} else {
+				deletedCount++
+			}
+		}
+	}
+
+	if deletedCount > 0 {
+		log.Printf("Удалено %d старых файлов из GCS", deletedCount)
+	}
+
+	return nil
+}
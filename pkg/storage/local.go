@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// LocalStorage представляет хранилище в локальной директории
+type LocalStorage struct {
+	path string
+}
+
+// NewLocalStorage создает хранилище в директории EXPORT_PATH
+func NewLocalStorage(cfg *config.Config) (*LocalStorage, error) {
+	if err := os.MkdirAll(cfg.ExportPath, 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории экспорта: %v", err)
+	}
+	return &LocalStorage{path: cfg.ExportPath}, nil
+}
+
+// SaveFile сохраняет файл в локальную директорию
+func (s *LocalStorage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
+	if err := os.MkdirAll(s.path, 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.path, filename), data, 0644); err != nil {
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+	return nil
+}
+
+// GetFile возвращает содержимое файла из локальной директории
+func (s *LocalStorage) GetFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.path, filename))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	return data, err
+}
+
+// ListFiles возвращает список файлов из локальной директории
+func (s *LocalStorage) ListFiles() ([]models.ExportFile, error) {
+	files, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exportFiles []models.ExportFile
+	versions := make(metaVersions)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if IsMetaSidecar(file.Name()) {
+			if info, err := file.Info(); err == nil {
+				versions.add(file.Name(), info.ModTime(), info.Size())
+			}
+			continue
+		}
+		if !IsExportFile(file.Name()) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		// ProjectID и группа из имени файла — только для файлов без метаданных
+		projectID, groupName, _ := parseExportFilename(file.Name())
+
+		exportFiles = append(exportFiles, models.ExportFile{
+			Name:          file.Name(),
+			Size:          info.Size(),
+			ModifiedTime:  info.ModTime(),
+			FormattedSize: formatFileSize(info.Size()),
+			FormattedDate: info.ModTime().Format("02.01.2006 15:04:05"),
+			ProjectID:     projectID,
+			GroupName:     groupName,
+			Compression:   CompressionFromName(file.Name()),
+		})
+	}
+
+	versions.apply(exportFiles)
+	sortExportFiles(exportFiles)
+
+	return exportFiles, nil
+}
+
+// DeleteFile удаляет файл из локальной директории
+func (s *LocalStorage) DeleteFile(filename string) error {
+	return os.Remove(filepath.Join(s.path, filename))
+}
+
+// CleanupOldFiles удаляет файлы старше месяца из локальной директории, кроме закрепленных
+func (s *LocalStorage) CleanupOldFiles(keep func(filename string) bool) error {
+	files, err := os.ReadDir(s.path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения директории: %v", err)
+	}
+
+	monthAgo := time.Now().AddDate(0, -1, 0)
+
+	for _, file := range files {
+		if file.IsDir() || !IsExportFile(file.Name()) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().Before(monthAgo) && !keep(file.Name()) {
+			filePath := filepath.Join(s.path, file.Name())
+			if err := os.Remove(filePath); err != nil {
+				log.Printf("Ошибка удаления старого файла %s: %v", filePath, err)
+			} else {
+				log.Printf("Удален старый файл: %s", filePath)
+			}
+		}
+	}
+
+	return nil
+}
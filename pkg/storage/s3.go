@@ -2,13 +2,18 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"path/filepath"
+	"mime"
+	"net/url"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"testops-export/pkg/config"
 	"testops-export/pkg/models"
@@ -16,49 +21,88 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 // S3Storage представляет S3 хранилище
 type S3Storage struct {
-	client *s3.Client
-	bucket string
-	config *config.Config
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	config  *config.Config
+
+	// keys хранит соответствие имени файла и ключа объекта,
+	// так как ключ зависит от шаблона и не восстанавливается по имени
+	keysMu   sync.Mutex
+	keys     map[string]string
+	listedAt time.Time  // Время последнего перечитывания списка объектов
+	listMu   sync.Mutex // Перечитывание списка при промахе выполняется одно за раз
 }
 
+// relistInterval — как часто промах по имени файла может перечитывать список объектов.
+// Без ограничения каждый запрос несуществующего файла перечитывал бы весь бакет
+const relistInterval = 10 * time.Second
+
 // NewS3Storage создает новый экземпляр S3 хранилища
 func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
 	if !cfg.S3Enabled {
 		return nil, fmt.Errorf("S3 не включен в конфигурации")
 	}
 
-	var awsConfig aws.Config
-	var err error
+	if cfg.S3StorageClass != "" && !isKnownStorageClass(cfg.S3StorageClass) {
+		return nil, fmt.Errorf("неизвестный класс хранения S3: %s", cfg.S3StorageClass)
+	}
+	if _, err := parseTags(cfg.S3ExtraTags); err != nil {
+		return nil, fmt.Errorf("ошибка разбора S3_EXTRA_TAGS: %v", err)
+	}
 
-	creds := credentials.NewStaticCredentialsProvider(cfg.S3AccessKey, cfg.S3SecretKey, "")
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(cfg.S3Region),
+	}
 
 	if cfg.S3Endpoint != "" {
+		// Собственный endpoint используется только для S3, остальные сервисы (STS) — по умолчанию
 		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			if service != s3.ServiceID {
+				return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+			}
 			return aws.Endpoint{
 				URL: cfg.S3Endpoint,
 			}, nil
 		})
-		awsConfig, err = awsconfig.LoadDefaultConfig(context.TODO(),
-			awsconfig.WithEndpointResolverWithOptions(customResolver),
-			awsconfig.WithCredentialsProvider(creds),
-			awsconfig.WithRegion(cfg.S3Region),
-		)
-	} else {
-		awsConfig, err = awsconfig.LoadDefaultConfig(context.TODO(),
-			awsconfig.WithCredentialsProvider(creds),
-			awsconfig.WithRegion(cfg.S3Region),
-		)
+		opts = append(opts, awsconfig.WithEndpointResolverWithOptions(customResolver))
+	}
+
+	// Статические ключи задаются явно, иначе используется стандартная цепочка AWS:
+	// переменные окружения, web identity (IRSA), профиль, роль инстанса
+	if cfg.S3AccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		))
+	} else if cfg.S3Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(cfg.S3Profile))
 	}
 
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.TODO(), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка загрузки AWS конфигурации: %v", err)
 	}
 
+	// Дополнительно принимаем роль поверх полученных учетных данных
+	if cfg.S3AssumeRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsConfig), cfg.S3AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = cfg.S3AssumeRoleSessionName
+			if cfg.S3AssumeRoleExternalID != "" {
+				o.ExternalID = aws.String(cfg.S3AssumeRoleExternalID)
+			}
+		})
+		awsConfig.Credentials = aws.NewCredentialsCache(provider)
+		log.Printf("S3: используется роль %s", cfg.S3AssumeRoleARN)
+	}
+
 	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
 		o.UsePathStyle = true
 	})
@@ -71,38 +115,84 @@ func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
 		return nil, fmt.Errorf("ошибка доступа к S3 бакету %s: %v", cfg.S3Bucket, err)
 	}
 
+	// Временные ссылки подписываются для адреса, доступного из браузера
+	presign := s3.NewPresignClient(client)
+	if cfg.S3PublicEndpoint != "" {
+		publicConfig := awsConfig.Copy()
+		publicConfig.EndpointResolverWithOptions = aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			if service != s3.ServiceID {
+				return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+			}
+			return aws.Endpoint{
+				URL: cfg.S3PublicEndpoint,
+			}, nil
+		})
+		presign = s3.NewPresignClient(s3.NewFromConfig(publicConfig, func(o *s3.Options) {
+			o.UsePathStyle = true
+		}))
+	}
+
 	log.Printf("✅ S3 хранилище инициализировано: бакет %s", cfg.S3Bucket)
 
 	return &S3Storage{
-		client: client,
-		bucket: cfg.S3Bucket,
-		config: cfg,
+		client:  client,
+		presign: presign,
+		bucket:  cfg.S3Bucket,
+		config:  cfg,
+		keys:    make(map[string]string),
 	}, nil
 }
 
 // SaveFile сохраняет файл в S3
-func (s *S3Storage) SaveFile(data []byte, filename string) error {
+func (s *S3Storage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
 	ctx := context.TODO()
 
-	// Создаем ключ для S3 (путь к файлу)
-	key := s.generateS3Key(filename)
+	// Создаем ключ для S3 (путь к файлу) по шаблону
+	key := s.objectKey(filename, meta)
+	if IsMetaSidecar(filename) {
+		key = s.sidecarKey(filename, meta)
+	}
 
-	// Загружаем файл в S3
-	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
 		Key:         aws.String(key),
 		Body:        strings.NewReader(string(data)),
-		ContentType: aws.String("text/csv"),
+		ContentType: aws.String(ContentType(filename)),
 		Metadata: map[string]string{
 			"original-filename": filename,
 			"upload-time":       time.Now().Format(time.RFC3339),
 		},
-	})
+	}
+	if meta.Compression != "" {
+		input.Metadata["compression"] = meta.Compression
+	}
+
+	// Серверное шифрование
+	if s.config.S3SSE != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.config.S3SSE)
+		if s.config.S3KMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.config.S3KMSKeyID)
+		}
+	}
+
+	// Класс хранения
+	if s.config.S3StorageClass != "" {
+		input.StorageClass = types.StorageClass(s.config.S3StorageClass)
+	}
 
+	// Теги объекта для правил жизненного цикла
+	if tagging := s.objectTagging(meta); tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	// Загружаем файл в S3
+	_, err := s.client.PutObject(ctx, input)
 	if err != nil {
 		return fmt.Errorf("ошибка загрузки файла в S3: %v", err)
 	}
 
+	s.rememberKey(filename, key)
+
 	log.Printf("✅ Файл сохранен в S3: %s", key)
 	return nil
 }
@@ -110,12 +200,19 @@ func (s *S3Storage) SaveFile(data []byte, filename string) error {
 // GetFile возвращает содержимое файла из S3
 func (s *S3Storage) GetFile(filename string) ([]byte, error) {
 	ctx := context.TODO()
-	key := s.generateS3Key(filename)
+	key, err := s.resolveKey(filename)
+	if err != nil {
+		return nil, err
+	}
 
 	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения файла из S3: %v", err)
 	}
@@ -129,17 +226,39 @@ func (s *S3Storage) GetFile(filename string) ([]byte, error) {
 	return data, nil
 }
 
+// PresignGetURL возвращает временную ссылку на скачивание файла напрямую из S3
+func (s *S3Storage) PresignGetURL(filename string, expiry time.Duration) (string, error) {
+	key, err := s.resolveKey(filename)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := s.presign.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket:                     aws.String(s.bucket),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": filename})),
+		ResponseContentType:        aws.String(ContentType(filename)),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания временной ссылки S3: %v", err)
+	}
+
+	return req.URL, nil
+}
+
 // ListFiles возвращает список файлов из S3
 func (s *S3Storage) ListFiles() ([]models.ExportFile, error) {
 	ctx := context.TODO()
 
 	var files []models.ExportFile
 	var continuationToken *string
+	keys := make(map[string]string)
+	versions := make(metaVersions)
 
 	for {
 		input := &s3.ListObjectsV2Input{
 			Bucket: aws.String(s.bucket),
-			Prefix: aws.String("exports/"), // Префикс для файлов экспорта
+			Prefix: aws.String(s.listPrefix()), // Постоянная часть шаблона ключа
 		}
 
 		if continuationToken != nil {
@@ -152,28 +271,35 @@ func (s *S3Storage) ListFiles() ([]models.ExportFile, error) {
 		}
 
 		for _, obj := range result.Contents {
-			// Пропускаем директории и не-CSV файлы
-			if strings.HasSuffix(*obj.Key, "/") || !strings.HasSuffix(*obj.Key, ".csv") {
+			if strings.HasSuffix(*obj.Key, "/") {
 				continue
 			}
 
-			// Извлекаем имя файла из ключа
-			filename := filepath.Base(*obj.Key)
-
-			// Парсим ProjectID из имени файла
-			var projectID int64 = 0
-			parts := strings.Split(filename, "_")
-			if len(parts) > 2 {
-				projectID, _ = strconv.ParseInt(parts[2], 10, 64)
+			// Извлекаем имя файла из ключа. Ключи файлов метаданных запоминаем,
+			// но в список не включаем
+			filename := path.Base(*obj.Key)
+			if IsMetaSidecar(filename) {
+				keys[filename] = *obj.Key
+				versions.add(filename, *obj.LastModified, *obj.Size)
+				continue
+			}
+			if !IsExportFile(filename) {
+				continue
 			}
+			keys[filename] = *obj.Key
+
+			// Парсим ProjectID и группу из имени файла
+			projectID, groupName, _ := parseExportFilename(filename)
 
 			exportFile := models.ExportFile{
 				Name:          filename,
 				Size:          *obj.Size,
 				ModifiedTime:  *obj.LastModified,
-				FormattedSize: s.formatFileSize(*obj.Size),
+				FormattedSize: formatFileSize(*obj.Size),
 				FormattedDate: obj.LastModified.Format("02.01.2006 15:04:05"),
 				ProjectID:     projectID,
+				GroupName:     groupName,
+				Compression:   CompressionFromName(filename),
 			}
 			files = append(files, exportFile)
 		}
@@ -184,6 +310,12 @@ func (s *S3Storage) ListFiles() ([]models.ExportFile, error) {
 		continuationToken = result.NextContinuationToken
 	}
 
+	s.keysMu.Lock()
+	s.keys = keys
+	s.listedAt = time.Now()
+	s.keysMu.Unlock()
+
+	versions.apply(files)
 	// Сортируем по дате изменения (новые сверху)
 	sortExportFiles(files)
 
@@ -193,9 +325,12 @@ func (s *S3Storage) ListFiles() ([]models.ExportFile, error) {
 // DeleteFile удаляет файл из S3
 func (s *S3Storage) DeleteFile(filename string) error {
 	ctx := context.TODO()
-	key := s.generateS3Key(filename)
+	key, err := s.resolveKey(filename)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
@@ -204,12 +339,16 @@ func (s *S3Storage) DeleteFile(filename string) error {
 		return fmt.Errorf("ошибка удаления файла из S3: %v", err)
 	}
 
+	s.keysMu.Lock()
+	delete(s.keys, filename)
+	s.keysMu.Unlock()
+
 	log.Printf("✅ Файл удален из S3: %s", key)
 	return nil
 }
 
-// CleanupOldFiles удаляет файлы старше месяца из S3
-func (s *S3Storage) CleanupOldFiles() error {
+// CleanupOldFiles удаляет файлы старше месяца из S3, кроме закрепленных
+func (s *S3Storage) CleanupOldFiles(keep func(filename string) bool) error {
 	files, err := s.ListFiles()
 	if err != nil {
 		return fmt.Errorf("ошибка получения списка файлов для очистки: %v", err)
@@ -219,7 +358,7 @@ func (s *S3Storage) CleanupOldFiles() error {
 	deletedCount := 0
 
 	for _, file := range files {
-		if file.ModifiedTime.Before(monthAgo) {
+		if file.ModifiedTime.Before(monthAgo) && !keep(file.Name) {
 			if err := s.DeleteFile(file.Name); err != nil {
 				log.Printf("Ошибка удаления старого файла %s: %v", file.Name, err)
 			} else {
@@ -235,33 +374,189 @@ func (s *S3Storage) CleanupOldFiles() error {
 	return nil
 }
 
-// generateS3Key генерирует ключ для S3 на основе имени файла
-func (s *S3Storage) generateS3Key(filename string) string {
-	timestamp := time.Now().Format("2006-01-02")
-	return fmt.Sprintf("exports/%s/%s", timestamp, filename)
+// objectKey формирует ключ объекта по шаблону S3_KEY_TEMPLATE с префиксом S3_KEY_PREFIX.
+// Копии файлов в снимках релизов (и их метаданные) лежат отдельно: <постоянная часть шаблона>snapshots/<метка>/
+func (s *S3Storage) objectKey(filename string, meta models.ExportMetadata) string {
+	if IsServiceFile(filename) {
+		return s.serviceKey(filename)
+	}
+	if meta.Snapshot != "" {
+		return s.listPrefix() + "snapshots/" + meta.Snapshot + "/" + filename
+	}
+
+	created := meta.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	// Имя группы не должно порождать лишние уровни вложенности и небезопасные символы
+	group := SanitizeName(meta.GroupName)
+
+	key := strings.NewReplacer(
+		"{project}", strconv.FormatInt(meta.ProjectID, 10),
+		"{group}", group,
+		"{group_id}", strconv.Itoa(meta.GroupID),
+		"{run_id}", meta.RunID,
+		"{yyyy}", created.Format("2006"),
+		"{mm}", created.Format("01"),
+		"{dd}", created.Format("02"),
+		"{filename}", filename,
+	).Replace(s.config.S3KeyTemplate)
+
+	return s.config.S3KeyPrefix + key
 }
 
-// formatFileSize форматирует размер файла в читаемый вид
-func (s *S3Storage) formatFileSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
+// listPrefix возвращает постоянную часть ключа до первого плейсхолдера,
+// под которой лежат все файлы экспорта при любом шаблоне
+func (s *S3Storage) listPrefix() string {
+	prefix := s.config.S3KeyPrefix + s.config.S3KeyTemplate
+	if i := strings.Index(prefix, "{"); i >= 0 {
+		prefix = prefix[:i]
 	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return prefix[:i+1]
 	}
-	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+	return ""
 }
 
-// sortExportFiles сортирует файлы по дате изменения (новые сверху)
-func sortExportFiles(files []models.ExportFile) {
-	for i := 0; i < len(files)-1; i++ {
-		for j := i + 1; j < len(files); j++ {
-			if files[i].ModifiedTime.Before(files[j].ModifiedTime) {
-				files[i], files[j] = files[j], files[i]
-			}
+// serviceKey возвращает ключ служебного файла. Служебные файлы лежат по постоянному ключу
+// <постоянная часть шаблона>service/<имя>, чтобы другие реплики находили их без перечитывания списка
+func (s *S3Storage) serviceKey(filename string) string {
+	return s.listPrefix() + "service/" + filename
+}
+
+// rememberKey запоминает ключ объекта для имени файла
+func (s *S3Storage) rememberKey(filename, key string) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	s.keys[filename] = key
+}
+
+// resolveKey возвращает ключ объекта по имени файла. Ключи служебных файлов постоянны,
+// файл метаданных лежит рядом с файлом экспорта, а для прочих файлов при промахе список объектов
+// перечитывается не чаще relistInterval
+func (s *S3Storage) resolveKey(filename string) (string, error) {
+	if key, ok := s.knownKey(filename); ok {
+		return key, nil
+	}
+	if IsServiceFile(filename) {
+		return s.serviceKey(filename), nil
+	}
+	if IsMetaSidecar(filename) {
+		// Метаданные могли появиться после перечитывания списка (например, при закреплении
+		// на другой реплике), поэтому ключ выводится из ключа файла экспорта, а не ищется в списке.
+		// Если неизвестен и файл экспорта, метаданных нет
+		parent, ok := s.knownKey(strings.TrimSuffix(filename, metaSidecarExt))
+		if !ok {
+			return "", fmt.Errorf("%w: %s в S3", ErrNotFound, filename)
+		}
+		return keyBeside(parent, filename), nil
+	}
+
+	s.listMu.Lock()
+	defer s.listMu.Unlock()
+	s.keysMu.Lock()
+	key, ok := s.keys[filename]
+	fresh := time.Since(s.listedAt) < relistInterval
+	s.keysMu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !fresh {
+		if _, err := s.ListFiles(); err != nil {
+			return "", err
+		}
+		if key, ok := s.knownKey(filename); ok {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s в S3", ErrNotFound, filename)
+}
+
+// sidecarKey возвращает ключ файла метаданных: рядом с файлом экспорта, где его ищет resolveKey.
+// Метаданные файла без них (при закреплении, карантине) заполняются по списку и не совпадают
+// с теми, по которым строился ключ файла, поэтому шаблон применяется, только если файл экспорта
+// еще не сохранен (метаданные пишутся первыми)
+func (s *S3Storage) sidecarKey(filename string, meta models.ExportMetadata) string {
+	parent := strings.TrimSuffix(filename, metaSidecarExt)
+	if key, ok := s.knownKey(parent); ok {
+		return keyBeside(key, filename)
+	}
+	return keyBeside(s.objectKey(parent, meta), filename)
+}
+
+// keyBeside возвращает ключ файла в том же «каталоге», что и ключ key
+func keyBeside(key, filename string) string {
+	return key[:strings.LastIndex(key, "/")+1] + filename
+}
+
+// knownKey возвращает ключ объекта из сохраненных и перечисленных файлов
+func (s *S3Storage) knownKey(filename string) (string, bool) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	key, ok := s.keys[filename]
+	return key, ok
+}
+
+// objectTagging формирует строку тегов объекта в формате URL query
+func (s *S3Storage) objectTagging(meta models.ExportMetadata) string {
+	// Формат тегов проверен в NewS3Storage
+	tags, _ := parseTags(s.config.S3ExtraTags)
+	if s.config.S3TagObjects {
+		tags.Set("project-id", strconv.FormatInt(meta.ProjectID, 10))
+		tags.Set("group-id", strconv.Itoa(meta.GroupID))
+		// Имя группы задается пользователем и может не подходить для тега — загрузка из-за этого падать не должна
+		if group := sanitizeTagValue(meta.GroupName); group != "" {
+			tags.Set("group", group)
+		}
+	}
+	return tags.Encode()
+}
+
+// maxTagValueLen — максимальная длина значения тега объекта S3 в символах
+const maxTagValueLen = 256
+
+// sanitizeTagValue приводит значение тега к допустимому в S3: буквы, цифры, пробелы и символы + - = . _ : / @
+// остаются, прочие заменяются на "_", значение обрезается до maxTagValueLen символов
+func sanitizeTagValue(value string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range value {
+		if n == maxTagValueLen {
+			break
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune("+-=._:/@", r) {
+			r = '_'
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// parseTags разбирает теги вида "key1=value1,key2=value2"
+func parseTags(raw string) (url.Values, error) {
+	tags := url.Values{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("неверный формат тега: %s", pair)
+		}
+		tags.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return tags, nil
+}
+
+// isKnownStorageClass проверяет, что класс хранения поддерживается S3
+func isKnownStorageClass(class string) bool {
+	for _, c := range types.StorageClass("").Values() {
+		if string(c) == class {
+			return true
 		}
 	}
+	return false
 }
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// translit — транслитерация кириллицы (упрощенная ГОСТ 7.79-2000, система Б)
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// SanitizeName приводит произвольную строку (например, имя группы) к безопасному виду
+// для имени файла и ключа объекта: кириллица транслитерируется, пробелы и прочие
+// недопустимые символы заменяются на «-». Результат детерминирован и не бывает пустым.
+func SanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		lower := unicode.ToLower(r)
+		if t, ok := translit[lower]; ok {
+			if r != lower && t != "" {
+				t = strings.ToUpper(t[:1]) + t[1:]
+			}
+			b.WriteString(t)
+			continue
+		}
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+
+	// Схлопываем повторяющиеся «-» и «.» (имя не должно содержать «..»),
+	// затем убираем их по краям
+	var out strings.Builder
+	var prev rune
+	for _, r := range b.String() {
+		if (r == '-' || r == '.') && r == prev {
+			continue
+		}
+		prev = r
+		out.WriteRune(r)
+	}
+	result := strings.Trim(out.String(), "-.")
+	if result == "" {
+		return "group"
+	}
+	return result
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// Storage описывает хранилище файлов экспорта
+type Storage interface {
+	// SaveFile сохраняет содержимое экспорта под указанным именем
+	SaveFile(data []byte, filename string, meta models.ExportMetadata) error
+	// GetFile возвращает содержимое файла экспорта (ErrNotFound, если файла нет)
+	GetFile(filename string) ([]byte, error)
+	// ListFiles возвращает список файлов экспорта (новые сверху). Для файлов с метаданными
+	// заполняется MetaVersion по данным того же списка
+	ListFiles() ([]models.ExportFile, error)
+	// DeleteFile удаляет файл экспорта
+	DeleteFile(filename string) error
+	// CleanupOldFiles удаляет файлы экспорта старше месяца, кроме тех, для которых keep
+	// возвращает true (закрепленные). Файлы метаданных удаляет менеджер.
+	CleanupOldFiles(keep func(filename string) bool) error
+}
+
+// ErrNotFound возвращается GetFile, если файла нет в хранилище. Отличает отсутствие файла
+// (например, метаданных у старых файлов) от ошибки чтения
+var ErrNotFound = errors.New("файл не найден в хранилище")
+
+// Presigner реализуется хранилищами, умеющими выдавать временные ссылки на скачивание
+type Presigner interface {
+	PresignGetURL(filename string, expiry time.Duration) (string, error)
+}
+
+// New создает хранилище в соответствии с конфигурацией.
+// Если удаленное хранилище не включено, используется локальная директория.
+func New(cfg *config.Config) (Storage, error) {
+	switch {
+	case cfg.S3Enabled:
+		return NewS3Storage(cfg)
+	case cfg.WebDAVEnabled:
+		return NewWebDAVStorage(cfg)
+	case cfg.GCSEnabled:
+		return NewGCSStorage(cfg)
+	case cfg.AzureEnabled:
+		return NewAzureStorage(cfg)
+	default:
+		return NewLocalStorage(cfg)
+	}
+}
+
+// metaSidecarExt — расширение файла метаданных, который хранится рядом с каждым файлом
+const metaSidecarExt = ".meta.json"
+
+// MetaSidecarName возвращает имя файла метаданных для файла экспорта
+func MetaSidecarName(filename string) string {
+	return filename + metaSidecarExt
+}
+
+// IsMetaSidecar проверяет, что имя относится к файлу метаданных
+func IsMetaSidecar(filename string) bool {
+	return strings.HasSuffix(filename, metaSidecarExt)
+}
+
+// metaVersions собирает версии файлов метаданных при чтении списка: по версии менеджер
+// понимает, что метаданные не менялись, и не читает их заново
+type metaVersions map[string]string
+
+// add запоминает версию файла метаданных (время изменения и размер) для его файла экспорта
+func (v metaVersions) add(sidecar string, modified time.Time, size int64) {
+	v[strings.TrimSuffix(sidecar, metaSidecarExt)] = fmt.Sprintf("%d:%d", modified.UnixNano(), size)
+}
+
+// apply заполняет MetaVersion у файлов списка
+func (v metaVersions) apply(files []models.ExportFile) {
+	for i := range files {
+		files[i].MetaVersion = v[files[i].Name]
+	}
+}
+
+// Имена групп, под которыми хранятся служебные файлы запуска
+const (
+	BundleGroupName    = "bundle"
+	ManifestGroupName  = "manifest"
+	RunStatusGroupName = "run"
+	ScrubGroupName     = "scrub"
+)
+
+// BundleFilename возвращает имя ZIP архива запуска вида
+// testops_bundle_<project|all>_<run_id>.zip (projectID 0 — архив всех проектов)
+func BundleFilename(projectID int64, runID string) string {
+	return runFilename("bundle", projectID, runID, ".zip")
+}
+
+// ManifestFilename возвращает имя манифеста запуска вида
+// testops_manifest_<project|all>_<run_id>.json (projectID 0 — все проекты)
+func ManifestFilename(projectID int64, runID string) string {
+	return runFilename("manifest", projectID, runID, ".json")
+}
+
+// runStatusPrefix — префикс имен файлов состояния запуска
+const runStatusPrefix = "testops_run_"
+
+// RunStatusFilename возвращает имя файла состояния запуска вида testops_run_<run_id>.json.
+// Состояние хранится рядом с экспортами, чтобы запуск был виден всем репликам
+func RunStatusFilename(runID string) string {
+	return runStatusPrefix + runID + ".json"
+}
+
+// IsRunStatus проверяет, что имя относится к файлу состояния запуска
+func IsRunStatus(filename string) bool {
+	return strings.HasPrefix(filename, runStatusPrefix) && strings.HasSuffix(filename, ".json") && !IsMetaSidecar(filename)
+}
+
+// RunIDFromStatus возвращает ID запуска из имени файла состояния
+func RunIDFromStatus(filename string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filename, runStatusPrefix), ".json")
+}
+
+// ScrubReportFilename — имя файла с отчетом последней проверки целостности
+const ScrubReportFilename = "testops_scrub_report.json"
+
+// IsServiceFile проверяет, что имя относится к служебному файлу (состоянию запуска или отчету
+// проверки), а не к экспорту
+func IsServiceFile(filename string) bool {
+	return IsRunStatus(filename) || filename == ScrubReportFilename
+}
+
+// IsBundle проверяет, что имя относится к ZIP архиву запуска
+func IsBundle(filename string) bool {
+	return strings.HasPrefix(filename, "testops_bundle_") && strings.HasSuffix(filename, ".zip")
+}
+
+// IsManifest проверяет, что имя относится к манифесту запуска
+func IsManifest(filename string) bool {
+	return strings.HasPrefix(filename, "testops_manifest_") && strings.HasSuffix(filename, ".json") && !IsMetaSidecar(filename)
+}
+
+// snapshotPrefix — префикс имен копий файлов в снимках релизов
+const snapshotPrefix = "testops_snapshot_"
+
+// SnapshotFilename возвращает имя копии файла в снимке вида
+// testops_snapshot_<метка>__<исходное имя> (метка должна быть приведена SanitizeName)
+func SnapshotFilename(label, filename string) string {
+	return snapshotPrefix + label + "__" + filename
+}
+
+// IsSnapshotFile проверяет, что имя относится к копии файла в снимке
+func IsSnapshotFile(filename string) bool {
+	return strings.HasPrefix(filename, snapshotPrefix) && strings.Contains(filename, "__")
+}
+
+// SnapshotSourceName возвращает исходное имя файла для копии в снимке (для прочих имен — само имя)
+func SnapshotSourceName(filename string) string {
+	if !IsSnapshotFile(filename) {
+		return filename
+	}
+	return filename[strings.Index(filename, "__")+2:]
+}
+
+// SnapshotLabel возвращает метку снимка из имени копии файла
+func SnapshotLabel(filename string) string {
+	if !IsSnapshotFile(filename) {
+		return ""
+	}
+	return strings.TrimPrefix(filename[:strings.Index(filename, "__")], snapshotPrefix)
+}
+
+func runFilename(kind string, projectID int64, runID, ext string) string {
+	scope := "all"
+	if projectID != 0 {
+		scope = strconv.FormatInt(projectID, 10)
+	}
+	return fmt.Sprintf("testops_%s_%s_%s%s", kind, scope, runID, ext)
+}
+
+// parseExportFilename извлекает ID проекта и имя группы из имени файла
+// вида testops_export_<project>_<group>_<YYYY-MM-DD>_<HH-MM-SS>.csv[.gz|.zst].
+// Для ZIP архивов и манифестов запуска возвращаются группы BundleGroupName и ManifestGroupName,
+// для файлов метаданных и копий в снимках — данные исходного файла.
+// Используется только для файлов без метаданных и для раскладки по папкам.
+func parseExportFilename(filename string) (int64, string, error) {
+	filename = SnapshotSourceName(strings.TrimSuffix(filename, metaSidecarExt))
+	if IsBundle(filename) || IsManifest(filename) {
+		groupName := BundleGroupName
+		if IsManifest(filename) {
+			groupName = ManifestGroupName
+		}
+		parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(filename, ".zip"), ".json"), "_")
+		if len(parts) != 4 {
+			return 0, "", fmt.Errorf("неверный формат имени файла запуска: %s", filename)
+		}
+		if parts[2] == "all" {
+			return 0, groupName, nil
+		}
+		projectID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("неверный ID проекта в имени файла %s: %v", filename, err)
+		}
+		return projectID, groupName, nil
+	}
+	parts := strings.Split(strings.TrimSuffix(UncompressedName(filename), ".csv"), "_")
+	if len(parts) < 6 {
+		return 0, "", fmt.Errorf("неверный формат имени файла: %s", filename)
+	}
+	projectID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("неверный ID проекта в имени файла %s: %v", filename, err)
+	}
+	return projectID, strings.Join(parts[3:len(parts)-2], "_"), nil
+}
+
+// ProjectFromFilename возвращает ID проекта из имени файла (0 — файл всех проектов или имя не разобрано)
+func ProjectFromFilename(filename string) int64 {
+	projectID, _, _ := parseExportFilename(filename)
+	return projectID
+}
+
+// metadataOrFilename возвращает ID проекта и группу из метаданных объекта,
+// а для объектов без метаданных — из имени файла
+func metadataOrFilename(filename, projectIDMeta, groupNameMeta string) (int64, string) {
+	projectID, groupName, _ := parseExportFilename(filename)
+	if id, err := strconv.ParseInt(projectIDMeta, 10, 64); err == nil {
+		projectID = id
+	}
+	if groupNameMeta != "" {
+		groupName = groupNameMeta
+	}
+	return projectID, groupName
+}
+
+// formatFileSize форматирует размер файла в читаемый вид
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// sortExportFiles сортирует файлы по дате изменения (новые сверху)
+func sortExportFiles(files []models.ExportFile) {
+	for i := 0; i < len(files)-1; i++ {
+		for j := i + 1; j < len(files); j++ {
+			if files[i].ModifiedTime.Before(files[j].ModifiedTime) {
+				files[i], files[j] = files[j], files[i]
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExportFilename(t *testing.T) {
+	tests := []struct {
+		filename  string
+		projectID int64
+		group     string
+		wantErr   bool
+	}{
+		{"testops_export_17_API_2025-07-15_12-02-56.csv", 17, "API", false},
+		{"testops_export_17_Seller_Analytics_2025-07-15_12-02-56.csv.gz", 17, "Seller_Analytics", false},
+		{"testops_export_15_UI_2025-07-15_12-02-56.csv.zst", 15, "UI", false},
+		{"testops_export_17_API_2025-07-15_12-02-56.csv.meta.json", 17, "API", false},
+		{"testops_snapshot_v1.2__testops_export_17_API_2025-07-15_12-02-56.csv", 17, "API", false},
+		{"testops_bundle_17_20250715-120256-000000001-a1b2c3.zip", 17, BundleGroupName, false},
+		{"testops_bundle_all_20250715-120256-000000001-a1b2c3.zip", 0, BundleGroupName, false},
+		{"testops_manifest_all_20250715-120256-000000001-a1b2c3.json", 0, ManifestGroupName, false},
+		{"testops_manifest_17_20250715-120256-000000001-a1b2c3.json.meta.json", 17, ManifestGroupName, false},
+		{"testops_bundle_x_20250715.zip", 0, "", true},
+		{"testops_bundle_17_extra_20250715.zip", 0, "", true},
+		{"testops_export_x_API_2025-07-15_12-02-56.csv", 0, "", true},
+		{"report.csv", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			projectID, group, err := parseExportFilename(tt.filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка %v, ошибка ожидалась: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if projectID != tt.projectID || group != tt.group {
+				t.Errorf("получено %d/%q, ожидалось %d/%q", projectID, group, tt.projectID, tt.group)
+			}
+		})
+	}
+}
+
+func TestSanitizeTagValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"латиница", "API", "API"},
+		{"пробелы", "Seller Analytics", "Seller Analytics"},
+		{"кириллица и допустимые символы", "Регресс: web/mobile", "Регресс: web/mobile"},
+		{"все допустимые знаки", "a+b=c.d_e-f@g", "a+b=c.d_e-f@g"},
+		{"недопустимые знаки", "API & UI (smoke)", "API _ UI _smoke_"},
+		{"пробелы по краям", "  группа  ", "группа"},
+		{"пустое значение", "", ""},
+		{"длинное значение", strings.Repeat("я", 300), strings.Repeat("я", maxTagValueLen)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeTagValue(tt.value); got != tt.want {
+				t.Errorf("sanitizeTagValue(%q) = %q, ожидалось %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// WebDAVStorage представляет WebDAV хранилище (например, Nextcloud).
+// Файлы раскладываются по папкам проектов: <WEBDAV_URL>/<project_id>/<filename>,
+// служебные файлы — в папку <WEBDAV_URL>/service/
+type WebDAVStorage struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+
+	// Папки проектов, в которых лежат файлы (имя файла -> папка)
+	dirsMu   sync.Mutex
+	dirs     map[string]string
+	listedAt time.Time  // Время последнего перечитывания списка файлов
+	listMu   sync.Mutex // Перечитывание списка при промахе выполняется одно за раз
+}
+
+// webdavServiceDir — папка служебных файлов
+const webdavServiceDir = "service"
+
+// propfindBody запрашивает только нужные для списка файлов свойства
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+  </d:prop>
+</d:propfind>`
+
+// davMultistatus описывает ответ PROPFIND
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// davEntry представляет элемент коллекции WebDAV
+type davEntry struct {
+	Name         string
+	IsDir        bool
+	Size         int64
+	ModifiedTime time.Time
+}
+
+// NewWebDAVStorage создает новый экземпляр WebDAV хранилища
+func NewWebDAVStorage(cfg *config.Config) (*WebDAVStorage, error) {
+	if !cfg.WebDAVEnabled {
+		return nil, fmt.Errorf("WebDAV не включен в конфигурации")
+	}
+
+	s := &WebDAVStorage{
+		client:   &http.Client{Timeout: 60 * time.Second},
+		baseURL:  strings.TrimRight(cfg.WebDAVURL, "/"),
+		username: cfg.WebDAVUsername,
+		password: cfg.WebDAVPassword,
+		dirs:     make(map[string]string),
+	}
+
+	// Проверяем доступность корневой папки, при необходимости создаем её
+	if _, err := s.propfind(""); err != nil {
+		if err := s.mkcol(""); err != nil {
+			return nil, fmt.Errorf("ошибка доступа к WebDAV %s: %v", s.baseURL, err)
+		}
+	}
+
+	log.Printf("✅ WebDAV хранилище инициализировано: %s", s.baseURL)
+
+	return s, nil
+}
+
+// SaveFile сохраняет файл в папку проекта
+func (s *WebDAVStorage) SaveFile(data []byte, filename string, meta models.ExportMetadata) error {
+	dir := strconv.FormatInt(meta.ProjectID, 10)
+	if IsServiceFile(filename) {
+		dir = webdavServiceDir
+	}
+	if IsMetaSidecar(filename) {
+		// Метаданные лежат в папке файла экспорта, где их ищет filePath: проект в метаданных
+		// файла без них заполняется по имени и может не совпадать с папкой
+		if parentDir, ok := s.knownDir(strings.TrimSuffix(filename, metaSidecarExt)); ok {
+			dir = parentDir
+		}
+	}
+	if err := s.mkcol(dir); err != nil {
+		return fmt.Errorf("ошибка создания папки проекта в WebDAV: %v", err)
+	}
+
+	resp, err := s.do("PUT", path.Join(dir, filename), bytes.NewReader(data), map[string]string{
+		"Content-Type": ContentType(filename),
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки файла в WebDAV: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ошибка загрузки файла в WebDAV: %d - %s", resp.StatusCode, string(body))
+	}
+
+	s.rememberDir(filename, dir)
+	log.Printf("✅ Файл сохранен в WebDAV: %s/%s", dir, filename)
+	return nil
+}
+
+// GetFile возвращает содержимое файла из WebDAV
+func (s *WebDAVStorage) GetFile(filename string) ([]byte, error) {
+	filePath, err := s.filePath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do("GET", filePath, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения файла из WebDAV: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка получения файла из WebDAV: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения содержимого файла: %v", err)
+	}
+
+	return data, nil
+}
+
+// ListFiles возвращает список файлов из папок проектов
+func (s *WebDAVStorage) ListFiles() ([]models.ExportFile, error) {
+	dirs, err := s.propfind("")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка папок из WebDAV: %v", err)
+	}
+
+	var files []models.ExportFile
+	found := make(map[string]string)
+	versions := make(metaVersions)
+	for _, dir := range dirs {
+		if !dir.IsDir {
+			continue
+		}
+
+		entries, err := s.propfind(dir.Name)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка файлов из WebDAV: %v", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir {
+				continue
+			}
+			found[entry.Name] = dir.Name
+			if IsMetaSidecar(entry.Name) {
+				versions.add(entry.Name, entry.ModifiedTime, entry.Size)
+				continue
+			}
+			if !IsExportFile(entry.Name) {
+				continue
+			}
+
+			// Парсим ProjectID и группу из имени файла
+			projectID, groupName, _ := parseExportFilename(entry.Name)
+
+			files = append(files, models.ExportFile{
+				Name:          entry.Name,
+				Size:          entry.Size,
+				ModifiedTime:  entry.ModifiedTime,
+				FormattedSize: formatFileSize(entry.Size),
+				FormattedDate: entry.ModifiedTime.Format("02.01.2006 15:04:05"),
+				ProjectID:     projectID,
+				GroupName:     groupName,
+				Compression:   CompressionFromName(entry.Name),
+			})
+		}
+	}
+
+	s.dirsMu.Lock()
+	s.dirs = found
+	s.listedAt = time.Now()
+	s.dirsMu.Unlock()
+
+	versions.apply(files)
+	sortExportFiles(files)
+
+	return files, nil
+}
+
+// DeleteFile удаляет файл из WebDAV
+func (s *WebDAVStorage) DeleteFile(filename string) error {
+	filePath, err := s.filePath(filename)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do("DELETE", filePath, nil, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления файла из WebDAV: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ошибка удаления файла из WebDAV: %d", resp.StatusCode)
+	}
+
+	log.Printf("✅ Файл удален из WebDAV: %s", filePath)
+	return nil
+}
+
+// CleanupOldFiles удаляет файлы старше месяца из WebDAV, кроме закрепленных
+func (s *WebDAVStorage) CleanupOldFiles(keep func(filename string) bool) error {
+	files, err := s.ListFiles()
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка файлов для очистки: %v", err)
+	}
+
+	monthAgo := time.Now().AddDate(0, -1, 0)
+	deletedCount := 0
+
+	for _, file := range files {
+		if file.ModifiedTime.Before(monthAgo) && !keep(file.Name) {
+			if err := s.DeleteFile(file.Name); err != nil {
+				log.Printf("Ошибка удаления старого файла %s: %v", file.Name, err)
+			} else {
+				deletedCount++
+			}
+		}
+	}
+
+	if deletedCount > 0 {
+		log.Printf("Удалено %d старых файлов из WebDAV", deletedCount)
+	}
+
+	return nil
+}
+
+// rememberDir запоминает папку проекта для имени файла
+func (s *WebDAVStorage) rememberDir(filename, dir string) {
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+	s.dirs[filename] = dir
+}
+
+// filePath возвращает путь файла относительно корня с учетом папки проекта.
+// Папка берется из сохраненных и перечисленных файлов. Служебные файлы лежат в постоянной папке,
+// файл метаданных — рядом с файлом экспорта, а для прочих файлов при промахе список перечитывается
+// не чаще relistInterval; для имен старого формата папка определяется по ID проекта в имени.
+func (s *WebDAVStorage) filePath(filename string) (string, error) {
+	if dir, ok := s.knownDir(filename); ok {
+		return path.Join(dir, filename), nil
+	}
+	if IsServiceFile(filename) {
+		return path.Join(webdavServiceDir, filename), nil
+	}
+	if IsMetaSidecar(filename) {
+		// Метаданные могли появиться после перечитывания списка, поэтому папка берется
+		// у файла экспорта. Если неизвестен и файл экспорта, метаданных нет
+		dir, ok := s.knownDir(strings.TrimSuffix(filename, metaSidecarExt))
+		if !ok {
+			return "", fmt.Errorf("%w: %s в WebDAV", ErrNotFound, filename)
+		}
+		return path.Join(dir, filename), nil
+	}
+
+	s.listMu.Lock()
+	defer s.listMu.Unlock()
+	s.dirsMu.Lock()
+	dir, ok := s.dirs[filename]
+	fresh := time.Since(s.listedAt) < relistInterval
+	s.dirsMu.Unlock()
+	if ok {
+		return path.Join(dir, filename), nil
+	}
+	if !fresh {
+		if _, err := s.ListFiles(); err != nil {
+			return "", err
+		}
+		if dir, ok := s.knownDir(filename); ok {
+			return path.Join(dir, filename), nil
+		}
+	}
+
+	projectID, _, err := parseExportFilename(filename)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s в WebDAV", ErrNotFound, filename)
+	}
+	return path.Join(strconv.FormatInt(projectID, 10), filename), nil
+}
+
+// knownDir возвращает папку файла из сохраненных и перечисленных файлов
+func (s *WebDAVStorage) knownDir(filename string) (string, bool) {
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+	dir, ok := s.dirs[filename]
+	return dir, ok
+}
+
+// fileURL возвращает полный URL для пути относительно корня
+func (s *WebDAVStorage) fileURL(relPath string) string {
+	if relPath == "" {
+		return s.baseURL + "/"
+	}
+	var escaped []string
+	for _, part := range strings.Split(relPath, "/") {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return s.baseURL + "/" + strings.Join(escaped, "/")
+}
+
+// do выполняет запрос к WebDAV серверу с базовой авторизацией
+func (s *WebDAVStorage) do(method, relPath string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.fileURL(relPath), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return s.client.Do(req)
+}
+
+// mkcol создает папку, если её ещё нет
+func (s *WebDAVStorage) mkcol(relPath string) error {
+	resp, err := s.do("MKCOL", relPath, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 405 Method Not Allowed возвращается, если папка уже существует
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%d - %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// propfind возвращает содержимое папки (без самой папки)
+func (s *WebDAVStorage) propfind(relPath string) ([]davEntry, error) {
+	resp, err := s.do("PROPFIND", relPath, strings.NewReader(propfindBody), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%d - %s", resp.StatusCode, string(body))
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("ошибка разбора ответа PROPFIND: %v", err)
+	}
+
+	self, err := url.Parse(s.fileURL(relPath))
+	if err != nil {
+		return nil, err
+	}
+	selfPath := strings.TrimRight(self.Path, "/")
+
+	var entries []davEntry
+	for _, r := range ms.Responses {
+		// href может быть как абсолютным путем, так и полным URL
+		hrefURL, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		href := strings.TrimRight(hrefURL.Path, "/")
+		// В ответе присутствует и сама запрашиваемая папка
+		if href == selfPath {
+			continue
+		}
+
+		entry := davEntry{Name: path.Base(href)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, "200") {
+				continue
+			}
+			entry.IsDir = ps.Prop.ResourceType.Collection != nil
+			if ps.Prop.ContentLength != "" {
+				entry.Size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			}
+			if ps.Prop.LastModified != "" {
+				entry.ModifiedTime, _ = http.ParseTime(ps.Prop.LastModified)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
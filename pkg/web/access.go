@@ -0,0 +1,128 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"testops-export/pkg/models"
+)
+
+// Действия, на которые выдаются права в ACCESS_CONFIG
+const (
+	actionView     = "view"
+	actionDownload = "download"
+	actionTrigger  = "trigger"
+	actionDelete   = "delete"
+	actionAdmin    = "admin" // Все действия, а также снимки релизов и проверка целостности
+)
+
+// can проверяет, что пользователь запроса может выполнить действие над проектом.
+// projectID 0 — общие файлы и экспорт всех проектов, на них нужны права all_projects.
+// Без аутентификации или без ACCESS_CONFIG разрешено все.
+func (s *Server) can(r *http.Request, action string, projectID int64) bool {
+	user := currentUser(r)
+	if user == nil || len(s.config.AccessRules) == 0 {
+		return true
+	}
+	for _, rule := range s.config.AccessRules {
+		if ruleMatchesUser(rule, user) && ruleCoversProject(rule, projectID) && ruleAllows(rule, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAdmin проверяет права администратора на все проекты
+func (s *Server) canAdmin(r *http.Request) bool {
+	return s.can(r, actionAdmin, 0)
+}
+
+// visibleFiles оставляет файлы проектов, которые пользователь может просматривать
+func (s *Server) visibleFiles(r *http.Request, files []models.ExportFile) []models.ExportFile {
+	visible := files[:0:0]
+	for _, f := range files {
+		if s.can(r, actionView, f.ProjectID) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// visibleScrubReport возвращает отчет последней проверки целостности, в котором оставлены только
+// проблемы файлов проектов, доступных пользователю для просмотра. Администратор видит отчет целиком
+func (s *Server) visibleScrubReport(r *http.Request) *models.ScrubReport {
+	report := s.manager.LastScrubReport()
+	if report == nil || s.canAdmin(r) {
+		return report
+	}
+	visible := *report
+	visible.Problems = nil
+	visible.Corrupt, visible.Quarantined = 0, 0
+	for _, p := range report.Problems {
+		if !s.can(r, actionView, p.ProjectID) {
+			continue
+		}
+		visible.Problems = append(visible.Problems, p)
+		visible.Corrupt++
+		if p.Quarantined {
+			visible.Quarantined++
+		}
+	}
+	return &visible
+}
+
+// canAccessFile проверяет действие над файлом по проекту из его метаданных
+func (s *Server) canAccessFile(r *http.Request, action, filename string) bool {
+	return s.can(r, action, s.manager.FileProjectID(filename))
+}
+
+// forbid отвечает 403 на запрос без прав
+func forbid(w http.ResponseWriter, action string, projectID int64) {
+	http.Error(w, forbiddenMessage(action, projectID), http.StatusForbidden)
+}
+
+func forbiddenMessage(action string, projectID int64) string {
+	if projectID == 0 {
+		return fmt.Sprintf("Нет прав на действие %s для всех проектов", action)
+	}
+	return fmt.Sprintf("Нет прав на действие %s в проекте %d", action, projectID)
+}
+
+func ruleMatchesUser(rule models.AccessRule, user *models.User) bool {
+	for _, name := range rule.Users {
+		if name == user.Name || (user.Email != "" && name == user.Email) {
+			return true
+		}
+	}
+	for _, group := range rule.Groups {
+		for _, userGroup := range user.Groups {
+			if group == userGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ruleCoversProject(rule models.AccessRule, projectID int64) bool {
+	if rule.AllProjects {
+		return true
+	}
+	for _, id := range rule.Projects {
+		if id == projectID && id != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleAllows проверяет действие по правилу: admin разрешает все, а любое право
+// на проект включает просмотр его файлов
+func ruleAllows(rule models.AccessRule, action string) bool {
+	for _, a := range rule.Actions {
+		if a == action || a == actionAdmin || action == actionView {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []string
+		action  string
+		want    bool
+	}{
+		{"то же действие", []string{actionDelete}, actionDelete, true},
+		{"другое действие", []string{actionDownload}, actionDelete, false},
+		{"admin разрешает все", []string{actionAdmin}, actionTrigger, true},
+		{"любое право включает просмотр", []string{actionTrigger}, actionView, true},
+		{"просмотр не дает скачивания", []string{actionView}, actionDownload, false},
+		{"без действий", nil, actionView, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleAllows(models.AccessRule{Actions: tt.actions}, tt.action); got != tt.want {
+				t.Errorf("ruleAllows(%v, %s) = %v, ожидалось %v", tt.actions, tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+// accessServer возвращает сервер с правилами доступа:
+// alice — просмотр проекта 1, группа qa — удаление в проекте 2, root — admin на все проекты
+func accessServer() *Server {
+	return &Server{config: &config.Config{AccessRules: []models.AccessRule{
+		{Users: []string{"alice"}, Projects: []int64{1}, Actions: []string{actionView}},
+		{Groups: []string{"qa"}, Projects: []int64{2}, Actions: []string{actionDelete}},
+		{Users: []string{"root@example.com"}, AllProjects: true, Actions: []string{actionAdmin}},
+	}}}
+}
+
+// requestAs возвращает запрос от имени пользователя (nil — без аутентификации)
+func requestAs(user *models.User) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if user == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), userKey, user))
+}
+
+func TestCan(t *testing.T) {
+	s := accessServer()
+	tests := []struct {
+		name      string
+		user      *models.User
+		action    string
+		projectID int64
+		want      bool
+	}{
+		{"без аутентификации разрешено все", nil, actionDelete, 1, true},
+		{"просмотр своего проекта", &models.User{Name: "alice"}, actionView, 1, true},
+		{"скачивание без права", &models.User{Name: "alice"}, actionDownload, 1, false},
+		{"чужой проект", &models.User{Name: "alice"}, actionView, 2, false},
+		{"общие файлы без all_projects", &models.User{Name: "alice"}, actionView, 0, false},
+		{"право группы", &models.User{Name: "bob", Groups: []string{"dev", "qa"}}, actionDelete, 2, true},
+		{"право группы в чужом проекте", &models.User{Name: "bob", Groups: []string{"qa"}}, actionDelete, 1, false},
+		{"пользователь по email", &models.User{Name: "root", Email: "root@example.com"}, actionTrigger, 0, true},
+		{"неизвестный пользователь", &models.User{Name: "eve"}, actionView, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.can(requestAs(tt.user), tt.action, tt.projectID); got != tt.want {
+				t.Errorf("can(%s, %d) = %v, ожидалось %v", tt.action, tt.projectID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanWithoutAccessRules(t *testing.T) {
+	s := &Server{config: &config.Config{}}
+	if !s.can(requestAs(&models.User{Name: "eve"}), actionDelete, 5) {
+		t.Error("без ACCESS_CONFIG вошедшему пользователю должно быть разрешено все")
+	}
+}
+
+func TestVisibleFiles(t *testing.T) {
+	s := accessServer()
+	files := []models.ExportFile{
+		{Name: "a.csv", ProjectID: 1},
+		{Name: "b.csv", ProjectID: 2},
+		{Name: "all.zip", ProjectID: 0},
+		{Name: "c.csv", ProjectID: 1},
+	}
+	tests := []struct {
+		name string
+		user *models.User
+		want []string
+	}{
+		{"без аутентификации", nil, []string{"a.csv", "b.csv", "all.zip", "c.csv"}},
+		{"один проект", &models.User{Name: "alice"}, []string{"a.csv", "c.csv"}},
+		{"право группы", &models.User{Name: "bob", Groups: []string{"qa"}}, []string{"b.csv"}},
+		{"admin", &models.User{Name: "root", Email: "root@example.com"}, []string{"a.csv", "b.csv", "all.zip", "c.csv"}},
+		{"без прав", &models.User{Name: "eve"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range s.visibleFiles(requestAs(tt.user), files) {
+				got = append(got, f.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("visibleFiles = %v, ожидалось %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("visibleFiles = %v, ожидалось %v", got, tt.want)
+				}
+			}
+		})
+	}
+	if files[1].Name != "b.csv" {
+		t.Error("visibleFiles не должен менять исходный список")
+	}
+}
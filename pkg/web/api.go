@@ -0,0 +1,527 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/export"
+	"testops-export/pkg/models"
+)
+
+// apiPrefix — префикс версии REST API
+const apiPrefix = "/api/v1/"
+
+// openAPISpec — описание REST API в формате OpenAPI 3, отдается по /api/v1/openapi.json
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiError — тело ответа с ошибкой, одинаковое для всех методов API
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiFile описывает файл хранилища в ответах API
+type apiFile struct {
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"` // export, bundle, manifest
+	Size        int64     `json:"size"`
+	ModifiedAt  time.Time `json:"modified_at"`
+	ProjectID   int64     `json:"project_id"`
+	GroupID     int       `json:"group_id,omitempty"`
+	GroupName   string    `json:"group_name,omitempty"`
+	RunID       string    `json:"run_id,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`
+	Rows        int       `json:"rows,omitempty"`
+	Compression string    `json:"compression,omitempty"`
+	Pinned      bool      `json:"pinned"`
+	Quarantined bool      `json:"quarantined"`
+	Problem     string    `json:"problem,omitempty"`
+	DownloadURL string    `json:"download_url"`
+}
+
+// apiFileDetails — файл вместе с его метаданными из индекса
+type apiFileDetails struct {
+	apiFile
+	Metadata models.ExportMetadata `json:"metadata"`
+}
+
+// apiProject описывает проект из конфигурации
+type apiProject struct {
+	ProjectID int64                      `json:"project_id"`
+	TreeID    int                        `json:"tree_id"`
+	Groups    []models.ExportGroupConfig `json:"groups"`
+	Quota     int64                      `json:"quota,omitempty"` // Квота проекта в байтах, 0 — без ограничения
+}
+
+// apiSchedule описывает расписание и ближайший плановый экспорт
+type apiSchedule struct {
+	CronSchedule string    `json:"cron_schedule"`
+	Description  string    `json:"description"`
+	NextRun      time.Time `json:"next_run"`
+	SecondsUntil int64     `json:"seconds_until"`
+}
+
+// apiRunStarted — ответ на запуск экспорта
+type apiRunStarted struct {
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+// handleAPI разбирает путь /api/v1/... и вызывает обработчик ресурса
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	resource, id, _ := strings.Cut(path, "/")
+
+	if (r.Method == http.MethodPost || r.Method == http.MethodDelete) && !checkAPIMutation(w, r) {
+		return
+	}
+
+	switch {
+	case resource == "openapi.json" && id == "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPISpec)
+	case resource == "files" && id == "":
+		if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodDelete {
+			s.apiDeleteFiles(w, r)
+		} else {
+			s.apiListFiles(w, r)
+		}
+	case resource == "files":
+		if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodDelete {
+			s.apiDeleteFile(w, r, id)
+		} else {
+			s.apiGetFile(w, r, id)
+		}
+	case resource == "audit" && id == "":
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiAuditLog(w, r)
+		}
+	case resource == "exports" && id == "":
+		if allowMethod(w, r, http.MethodPost) {
+			s.apiStartExport(w, r)
+		}
+	case resource == "runs" && id == "":
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiListRuns(w, r)
+		}
+	case resource == "runs" && strings.HasSuffix(id, "/events"):
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiRunEvents(w, r, strings.TrimSuffix(id, "/events"))
+		}
+	case resource == "runs":
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiGetRun(w, r, id)
+		}
+	case resource == "projects" && id == "":
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiListProjects(w, r)
+		}
+	case resource == "schedule" && id == "":
+		if allowMethod(w, r, http.MethodGet) {
+			s.apiSchedule(w)
+		}
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found", "Метод API не найден")
+	}
+}
+
+// apiListFiles возвращает файлы экспорта с фильтрами
+// project_id, group_id, group, q, run_id, kind, pinned, since, until,
+// сортировкой sort, order и страницами page, per_page (число файлов — в X-Total-Count)
+func (s *Server) apiListFiles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	// Без page и per_page возвращаются все файлы, как до появления страниц
+	query, err := parseFileQuery(q, 0)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	files, err := s.manager.GetExportFiles()
+	if err != nil {
+		log.Printf("Ошибка чтения файлов для API: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "Ошибка чтения файлов")
+		return
+	}
+
+	page, total := query.apply(s.visibleFiles(r, files))
+	result := make([]apiFile, 0, len(page))
+	for _, f := range page {
+		result = append(result, toAPIFile(f))
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if query.perPage > 0 {
+		var links []string
+		if query.page > 1 {
+			links = append(links, fmt.Sprintf("<%s>; rel=\"prev\"", pageURL(r.URL, query.page-1)))
+		}
+		if query.page < query.pages(total) {
+			links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", pageURL(r.URL, query.page+1)))
+		}
+		if len(links) > 0 {
+			w.Header().Set("Link", strings.Join(links, ", "))
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// apiGetFile возвращает файл и его метаданные. Имя уже декодировано из пути запроса
+func (s *Server) apiGetFile(w http.ResponseWriter, r *http.Request, name string) {
+	if !isSafeFilename(name) {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "Недопустимое имя файла")
+		return
+	}
+	// Права проверяются до поиска файла, чтобы по 404 нельзя было узнать о файлах чужих проектов
+	if projectID := s.manager.FileProjectID(name); !s.can(r, actionView, projectID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionView, projectID))
+		return
+	}
+
+	f, meta, err := s.manager.GetExportFile(name)
+	if errors.Is(err, export.ErrFileNotFound) {
+		writeAPIError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка чтения файла %s для API: %v", name, err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "Ошибка чтения файлов")
+		return
+	}
+	if !s.can(r, actionView, f.ProjectID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionView, f.ProjectID))
+		return
+	}
+	writeJSON(w, http.StatusOK, apiFileDetails{apiFile: toAPIFile(f), Metadata: meta})
+}
+
+// apiDeleteFile удаляет один файл. Имя уже декодировано из пути запроса
+func (s *Server) apiDeleteFile(w http.ResponseWriter, r *http.Request, name string) {
+	result, err := s.deleteFiles(r, []string{name})
+	if err != nil {
+		log.Printf("Ошибка чтения файлов для API: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "Ошибка чтения файлов")
+		return
+	}
+	if len(result.Failed) > 0 {
+		failure := result.Failed[0]
+		writeAPIError(w, failure.status, deleteErrorCode(failure.status), failure.Error)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiDeleteFiles удаляет несколько файлов (?name=...&name=...) и возвращает итог по каждому
+func (s *Server) apiDeleteFiles(w http.ResponseWriter, r *http.Request) {
+	names := r.URL.Query()["name"]
+	if len(names) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "Укажите файлы параметрами name")
+		return
+	}
+	result, err := s.deleteFiles(r, names)
+	if err != nil {
+		log.Printf("Ошибка чтения файлов для API: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "Ошибка чтения файлов")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// deleteErrorCode возвращает код ошибки API для HTTP статуса неудачного удаления
+func deleteErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_parameter"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusConflict:
+		return "protected"
+	}
+	return "storage_error"
+}
+
+// apiAuditLog возвращает последние записи журнала аудита (?limit=, по умолчанию 100). Только для admin
+func (s *Server) apiAuditLog(w http.ResponseWriter, r *http.Request) {
+	if !s.canAdmin(r) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionAdmin, 0))
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeAPIError(w, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("некорректный limit: %s", v))
+			return
+		}
+		limit = n
+	}
+	records, err := s.manager.AuditLog(limit)
+	if err != nil {
+		log.Printf("%v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "Ошибка чтения журнала аудита")
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// apiStartExport запускает экспорт проекта (project_id), отдельных групп проекта (group_id, group_ids) или всех проектов
+func (s *Server) apiStartExport(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ProjectID int64 `json:"project_id"`
+		GroupID   int   `json:"group_id"`
+		GroupIDs  []int `json:"group_ids"`
+	}
+	// Пустое тело — экспорт всех проектов
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "Неверный формат запроса")
+		return
+	}
+	if !s.can(r, actionTrigger, body.ProjectID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionTrigger, body.ProjectID))
+		return
+	}
+	if body.GroupID != 0 {
+		body.GroupIDs = append(body.GroupIDs, body.GroupID)
+	}
+	if len(body.GroupIDs) > 0 && body.ProjectID == 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "Для экспорта отдельных групп укажите project_id")
+		return
+	}
+
+	runID, err := s.manager.StartExport(body.ProjectID, body.GroupIDs...)
+	if errors.Is(err, export.ErrRunExists) {
+		writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+
+	statusURL := apiPrefix + "runs/" + runID
+	w.Header().Set("Location", statusURL)
+	writeJSON(w, http.StatusAccepted, apiRunStarted{RunID: runID, Status: export.RunRunning, StatusURL: statusURL})
+}
+
+// apiListRuns возвращает последние запуски экспорта доступных проектов
+func (s *Server) apiListRuns(w http.ResponseWriter, r *http.Request) {
+	runs := []models.RunStatus{}
+	for _, run := range s.manager.GetRuns() {
+		if s.can(r, actionView, run.ProjectID) {
+			runs = append(runs, run)
+		}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// apiGetRun возвращает состояние запуска экспорта
+func (s *Server) apiGetRun(w http.ResponseWriter, r *http.Request, runID string) {
+	run, ok := s.manager.GetRun(runID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Запуск %s не найден", runID))
+		return
+	}
+	if !s.can(r, actionView, run.ProjectID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionView, run.ProjectID))
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// apiListProjects возвращает проекты и группы из конфигурации, доступные пользователю
+func (s *Server) apiListProjects(w http.ResponseWriter, r *http.Request) {
+	projects := make([]apiProject, 0, len(s.config.Projects))
+	for _, p := range s.config.Projects {
+		if !s.can(r, actionView, p.ProjectID) {
+			continue
+		}
+		quota := p.QuotaBytes
+		if quota == 0 {
+			quota = s.config.ProjectQuota
+		}
+		groups := p.Groups
+		if groups == nil {
+			groups = []models.ExportGroupConfig{}
+		}
+		projects = append(projects, apiProject{ProjectID: p.ProjectID, TreeID: p.TreeID, Groups: groups, Quota: quota})
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// apiSchedule возвращает расписание и время следующего планового экспорта
+func (s *Server) apiSchedule(w http.ResponseWriter) {
+	next := s.manager.GetNextExportInfo()
+	if next.HasError {
+		writeAPIError(w, http.StatusInternalServerError, "invalid_schedule", next.ErrorMessage)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiSchedule{
+		CronSchedule: s.config.CronSchedule,
+		Description:  formatCronSchedule(s.config.CronSchedule),
+		NextRun:      next.NextRunTime,
+		SecondsUntil: int64(next.TimeUntilNext.Seconds()),
+	})
+}
+
+// fileFilter — условия отбора файлов для списка на главной странице и в apiListFiles
+type fileFilter struct {
+	projectID int64
+	groupID   int
+	group     string
+	search    string // Часть имени файла, в нижнем регистре
+	runID     string
+	kind      string
+	pinned    *bool
+	since     time.Time
+	until     time.Time
+}
+
+// parseFileFilter разбирает параметры запроса списка файлов
+func parseFileFilter(q url.Values) (fileFilter, error) {
+	var f fileFilter
+	var err error
+	if v := q.Get("project_id"); v != "" {
+		if f.projectID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, fmt.Errorf("некорректный project_id: %s", v)
+		}
+	}
+	if v := q.Get("group_id"); v != "" {
+		if f.groupID, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("некорректный group_id: %s", v)
+		}
+	}
+	f.group = q.Get("group")
+	f.search = strings.ToLower(strings.TrimSpace(q.Get("q")))
+	f.runID = q.Get("run_id")
+	switch f.kind = q.Get("kind"); f.kind {
+	case "", "export", "bundle", "manifest":
+	default:
+		return f, fmt.Errorf("некорректный kind: %s (допустимо export, bundle, manifest)", f.kind)
+	}
+	if v := q.Get("pinned"); v != "" {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("некорректный pinned: %s", v)
+		}
+		f.pinned = &pinned
+	}
+	if v := q.Get("since"); v != "" {
+		if f.since, err = parseAPITime(v); err != nil {
+			return f, fmt.Errorf("некорректный since: %s", v)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.until, err = parseAPITime(v); err != nil {
+			return f, fmt.Errorf("некорректный until: %s", v)
+		}
+		// Дата без времени включается целиком
+		if len(v) == len("2006-01-02") {
+			f.until = f.until.AddDate(0, 0, 1)
+		}
+	}
+	return f, nil
+}
+
+// parseAPITime принимает время в RFC 3339 или дату 2006-01-02 (UTC)
+func parseAPITime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func (f fileFilter) match(file models.ExportFile) bool {
+	switch {
+	case f.projectID != 0 && file.ProjectID != f.projectID,
+		f.groupID != 0 && file.GroupID != f.groupID,
+		f.group != "" && !strings.EqualFold(file.GroupName, f.group),
+		f.search != "" && !strings.Contains(strings.ToLower(file.Name), f.search),
+		f.runID != "" && file.RunID != f.runID,
+		f.kind != "" && fileKind(file) != f.kind,
+		f.pinned != nil && file.Pinned != *f.pinned,
+		!f.since.IsZero() && file.ModifiedTime.Before(f.since),
+		!f.until.IsZero() && !file.ModifiedTime.Before(f.until):
+		return false
+	}
+	return true
+}
+
+// fileKind возвращает вид файла: export, bundle или manifest
+func fileKind(f models.ExportFile) string {
+	switch {
+	case f.Bundle:
+		return "bundle"
+	case f.Manifest:
+		return "manifest"
+	}
+	return "export"
+}
+
+func toAPIFile(f models.ExportFile) apiFile {
+	return apiFile{
+		Name:        f.Name,
+		Kind:        fileKind(f),
+		Size:        f.Size,
+		ModifiedAt:  f.ModifiedTime,
+		ProjectID:   f.ProjectID,
+		GroupID:     f.GroupID,
+		GroupName:   f.GroupName,
+		RunID:       f.RunID,
+		SHA256:      f.SHA256,
+		Rows:        f.Rows,
+		Compression: f.Compression,
+		Pinned:      f.Pinned,
+		Quarantined: f.Quarantined,
+		Problem:     f.Problem,
+		DownloadURL: "/download/" + url.PathEscape(f.Name),
+	}
+}
+
+// allowMethod проверяет метод запроса и отвечает 405, если он не поддерживается
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Метод не поддерживается")
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorBody{Code: code, Message: message}})
+}
@@ -0,0 +1,283 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// sessionCookie — имя cookie сессии пользователя
+const sessionCookie = "testops_session"
+
+// publicPaths открыты без аутентификации
+var publicPaths = map[string]bool{
+	"/healthz":                 true,
+	"/auth/login":              true,
+	"/auth/callback":           true,
+	"/auth/logout":             true,
+	apiPrefix + "openapi.json": true,
+}
+
+type contextKey int
+
+const userKey contextKey = iota
+
+// authMethod проверяет учетные данные запроса одним способом.
+// Возвращает nil, если запрос не содержит учетных данных этого способа или они неверны.
+type authMethod interface {
+	authenticate(r *http.Request) *models.User
+}
+
+// authenticator проверяет запросы всеми включенными способами (AUTH_METHODS)
+type authenticator struct {
+	cfg     *config.Config
+	methods []authMethod
+	session *sessionStore
+	oidc    *oidcAuth // nil, если вход через OIDC выключен
+}
+
+// newAuthenticator создает проверку запросов по конфигурации
+func newAuthenticator(cfg *config.Config) *authenticator {
+	a := &authenticator{cfg: cfg, session: newSessionStore(cfg)}
+	if cfg.AuthEnabled("token") {
+		a.methods = append(a.methods, tokenAuth{tokens: cfg.AuthTokens})
+	}
+	if cfg.AuthEnabled("basic") {
+		a.methods = append(a.methods, basicAuth{users: cfg.AuthBasicUsers})
+	}
+	if cfg.AuthEnabled("oidc") {
+		a.oidc = newOIDCAuth(cfg, a.session)
+		a.methods = append(a.methods, a.session)
+	}
+	return a
+}
+
+// enabled сообщает, включена ли аутентификация
+func (a *authenticator) enabled() bool {
+	return len(a.cfg.AuthMethods) > 0
+}
+
+// middleware пропускает только аутентифицированные запросы и кладет пользователя в контекст
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	if !a.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, m := range a.methods {
+			if user := m.authenticate(r); user != nil {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+				return
+			}
+		}
+		a.challenge(w, r)
+	})
+}
+
+// challenge отвечает на запрос без учетных данных: браузер отправляется на вход через OIDC,
+// остальным клиентам возвращается 401
+func (a *authenticator) challenge(w http.ResponseWriter, r *http.Request) {
+	api := strings.HasPrefix(r.URL.Path, apiPrefix)
+	if a.oidc != nil && !api && r.Method == http.MethodGet && r.Header.Get("Authorization") == "" {
+		http.Redirect(w, r, "/auth/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+	if a.cfg.AuthEnabled("basic") {
+		w.Header().Set("WWW-Authenticate", `Basic realm="TestOps Export", charset="UTF-8"`)
+	} else if a.cfg.AuthEnabled("token") {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="TestOps Export"`)
+	}
+	if api {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Требуется аутентификация")
+		return
+	}
+	http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+}
+
+// currentUser возвращает пользователя запроса (nil, если аутентификация выключена)
+func currentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userKey).(*models.User)
+	return user
+}
+
+// tokenAuth проверяет статический токен из заголовка Authorization: Bearer или X-API-Token
+type tokenAuth struct {
+	tokens map[string]string // токен → имя клиента
+}
+
+func (t tokenAuth) authenticate(r *http.Request) *models.User {
+	token := r.Header.Get("X-API-Token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return nil
+	}
+	// Сравниваем со всеми токенами за постоянное время
+	var name string
+	for known, client := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			name = client
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	return &models.User{Name: name, Method: "token"}
+}
+
+// basicAuth проверяет имя и пароль HTTP Basic. Пароль задается открыто или bcrypt хешем ($2a$, $2b$, $2y$)
+type basicAuth struct {
+	users map[string]string
+}
+
+func (b basicAuth) authenticate(r *http.Request) *models.User {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	expected, found := b.users[name]
+	if !found {
+		return nil
+	}
+	if strings.HasPrefix(expected, "$2") {
+		if bcrypt.CompareHashAndPassword([]byte(expected), []byte(password)) != nil {
+			return nil
+		}
+	} else if subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+		return nil
+	}
+	return &models.User{Name: name, Method: "basic"}
+}
+
+// sessionStore выдает и проверяет подписанные cookie сессии. Состояние сессии хранится
+// в самой cookie, поэтому сессии работают на всех репликах с одинаковым SESSION_SECRET.
+type sessionStore struct {
+	key []byte
+	ttl time.Duration
+}
+
+// sessionData — содержимое cookie сессии
+type sessionData struct {
+	User    models.User `json:"user"`
+	Expires int64       `json:"exp"`
+}
+
+func newSessionStore(cfg *config.Config) *sessionStore {
+	key := []byte(cfg.SessionSecret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+		if cfg.AuthEnabled("oidc") {
+			log.Println("⚠️ SESSION_SECRET не задан: сессии сбросятся при перезапуске и не будут работать на других репликах")
+		}
+	}
+	return &sessionStore{key: key, ttl: cfg.SessionTTL}
+}
+
+func (s *sessionStore) authenticate(r *http.Request) *models.User {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	var data sessionData
+	if !s.decode(sessionCookie, cookie.Value, &data) || data.User.Name == "" || time.Now().Unix() > data.Expires {
+		return nil
+	}
+	return &data.User
+}
+
+// start выдает cookie сессии пользователю
+func (s *sessionStore) start(w http.ResponseWriter, r *http.Request, user models.User) {
+	expires := time.Now().Add(s.ttl)
+	value := s.encode(sessionCookie, sessionData{User: user, Expires: expires.Unix()})
+	setCookie(w, r, sessionCookie, value, expires)
+}
+
+// end удаляет cookie сессии
+func (s *sessionStore) end(w http.ResponseWriter, r *http.Request) {
+	setCookie(w, r, sessionCookie, "", time.Unix(0, 0))
+}
+
+// encode сериализует значение и подписывает его HMAC-SHA256. Назначение (purpose) входит в подпись,
+// чтобы значение одной cookie нельзя было подставить в другую
+func (s *sessionStore) encode(purpose string, v interface{}) string {
+	payload, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.sign(purpose, payload))
+}
+
+// decode проверяет подпись и разбирает значение, выданное encode с тем же назначением
+func (s *sessionStore) decode(purpose, value string, v interface{}) bool {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	if !hmac.Equal(signature, s.sign(purpose, payload)) {
+		return false
+	}
+	return json.Unmarshal(payload, v) == nil
+}
+
+func (s *sessionStore) sign(purpose string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(purpose + "\x00"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// setCookie ставит cookie, недоступную скриптам. Флаг Secure — при работе за HTTPS
+func setCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// handleHealth отвечает на проверку живости без аутентификации
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// handleLogout завершает сессию и показывает страницу выхода
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.auth.session.end(w, r)
+	if s.auth.oidc == nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<!DOCTYPE html><html lang="ru"><head><meta charset="UTF-8"><title>Выход — TestOps Export Manager</title></head>` +
+		`<body style="font-family:sans-serif;text-align:center;margin-top:80px;"><p>Вы вышли из TestOps Export Manager.</p>` +
+		`<p><a href="/auth/login">Войти снова</a></p></body></html>`))
+}
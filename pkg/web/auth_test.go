@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"testops-export/pkg/models"
+)
+
+func TestTokenAuth(t *testing.T) {
+	auth := tokenAuth{tokens: map[string]string{"secret-ci": "ci", "secret-bi": "bi"}}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string // Пусто — запрос не аутентифицирован
+	}{
+		{"Bearer", map[string]string{"Authorization": "Bearer secret-ci"}, "ci"},
+		{"X-API-Token", map[string]string{"X-API-Token": "secret-bi"}, "bi"},
+		{"Bearer важнее X-API-Token", map[string]string{"Authorization": "Bearer secret-ci", "X-API-Token": "secret-bi"}, "ci"},
+		{"неверный токен", map[string]string{"Authorization": "Bearer wrong"}, ""},
+		{"префикс токена", map[string]string{"Authorization": "Bearer secret"}, ""},
+		{"другая схема", map[string]string{"Authorization": "Basic secret-ci"}, ""},
+		{"без заголовков", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			user := auth.authenticate(r)
+			if got := userName(user); got != tt.want {
+				t.Fatalf("пользователь %q, ожидался %q", got, tt.want)
+			}
+			if user != nil && user.Method != "token" {
+				t.Errorf("способ входа %q, ожидался token", user.Method)
+			}
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hashed-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := basicAuth{users: map[string]string{"admin": "plain-pass", "ops": string(hash)}}
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     string
+	}{
+		{"открытый пароль", "admin", "plain-pass", "admin"},
+		{"неверный открытый пароль", "admin", "wrong", ""},
+		{"bcrypt хеш", "ops", "hashed-pass", "ops"},
+		{"неверный пароль к хешу", "ops", "wrong", ""},
+		{"хеш вместо пароля", "ops", string(hash), ""},
+		{"неизвестный пользователь", "eve", "plain-pass", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetBasicAuth(tt.user, tt.password)
+			if got := userName(auth.authenticate(r)); got != tt.want {
+				t.Errorf("пользователь %q, ожидался %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("без заголовка", func(t *testing.T) {
+		if user := auth.authenticate(httptest.NewRequest(http.MethodGet, "/", nil)); user != nil {
+			t.Errorf("пользователь %q, ожидалось без входа", user.Name)
+		}
+	})
+}
+
+func TestSessionStore(t *testing.T) {
+	store := &sessionStore{key: []byte("session-secret"), ttl: time.Hour}
+	other := &sessionStore{key: []byte("other-secret"), ttl: time.Hour}
+	user := models.User{Name: "alice", Groups: []string{"qa"}, Method: "oidc"}
+	valid := store.encode(sessionCookie, sessionData{User: user, Expires: time.Now().Add(time.Hour).Unix()})
+	payload, sig, _ := strings.Cut(valid, ".")
+	data, _ := json.Marshal(sessionData{User: models.User{Name: "root"}, Expires: time.Now().Add(time.Hour).Unix()})
+	forged := base64.RawURLEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"действующая сессия", valid, "alice"},
+		{"истекшая сессия", store.encode(sessionCookie, sessionData{User: user, Expires: time.Now().Add(-time.Minute).Unix()}), ""},
+		{"другой ключ подписи", other.encode(sessionCookie, sessionData{User: user, Expires: time.Now().Add(time.Hour).Unix()}), ""},
+		{"другое назначение", store.encode(oidcStateCookie, sessionData{User: user, Expires: time.Now().Add(time.Hour).Unix()}), ""},
+		{"подмененные данные", forged + "." + sig, ""},
+		{"без подписи", payload, ""},
+		{"подпись не base64", payload + ".!!!", ""},
+		{"пустой пользователь", store.encode(sessionCookie, sessionData{Expires: time.Now().Add(time.Hour).Unix()}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.value})
+			if got := userName(store.authenticate(r)); got != tt.want {
+				t.Errorf("пользователь %q, ожидался %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// userName возвращает имя пользователя (пусто для nil)
+func userName(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Name
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// Защита изменяющих запросов от подделки с чужих сайтов (CSRF). Cookie сессии уходит и с запросами,
+// которые чужая страница отправляет формой, поэтому изменяющие запросы принимаются только с телом
+// application/json (форма его не отправит без CORS) и только со страниц самого сервиса
+
+// sameOrigin проверяет, что запрос отправлен со страниц сервиса. Браузеры сообщают источник
+// в Sec-Fetch-Site, более старые — в Origin. Запросы без этих заголовков (curl, клиенты API) — не из браузера
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && u.Host == r.Host
+}
+
+// jsonBody проверяет, что тело запроса передано как application/json. Запрос без тела
+// и без Content-Type тоже подходит: такие запросы форма с чужого сайта не отправляет
+func jsonBody(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return r.ContentLength == 0
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// checkMutation отклоняет изменяющий запрос страницы с чужого сайта или не в формате JSON
+func checkMutation(w http.ResponseWriter, r *http.Request) bool {
+	if !sameOrigin(r) {
+		http.Error(w, "Запрос с другого сайта отклонен", http.StatusForbidden)
+		return false
+	}
+	if !jsonBody(r) {
+		http.Error(w, "Ожидается Content-Type: application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
+
+// checkAPIMutation отклоняет изменяющий запрос API с чужого сайта или не в формате JSON
+func checkAPIMutation(w http.ResponseWriter, r *http.Request) bool {
+	if !sameOrigin(r) {
+		writeAPIError(w, http.StatusForbidden, "cross_origin", "Запрос с другого сайта отклонен")
+		return false
+	}
+	if !jsonBody(r) {
+		writeAPIError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Ожидается Content-Type: application/json")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"testops-export/pkg/export"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// deleteFailure описывает файл, который не удалось удалить
+type deleteFailure struct {
+	Name   string `json:"name"`
+	Error  string `json:"error"`
+	status int    // HTTP статус для удаления одного файла
+}
+
+// deleteResult — итог удаления набора файлов
+type deleteResult struct {
+	Deleted []string        `json:"deleted"`
+	Failed  []deleteFailure `json:"failed"`
+}
+
+// deleteFiles удаляет файлы с проверкой прав delete и записывает каждую попытку в журнал аудита
+func (s *Server) deleteFiles(r *http.Request, filenames []string) (deleteResult, error) {
+	result := deleteResult{Deleted: []string{}, Failed: []deleteFailure{}}
+	files, err := s.manager.GetExportFiles()
+	if err != nil {
+		return result, err
+	}
+	byName := make(map[string]models.ExportFile, len(files))
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+
+	for _, filename := range filenames {
+		if !isSafeFilename(filename) {
+			result.Failed = append(result.Failed, deleteFailure{Name: filename, Error: "Недопустимое имя файла", status: http.StatusBadRequest})
+			continue
+		}
+		// Права проверяются до поиска файла, чтобы по 404 и 403 нельзя было узнать, какие файлы
+		// есть в чужих проектах. Для отсутствующего файла проект берется из имени
+		f, ok := byName[filename]
+		if !ok {
+			f = models.ExportFile{Name: filename, ProjectID: storage.ProjectFromFilename(filename)}
+		}
+		rec := s.auditRecord(r, "delete", f)
+		if !s.can(r, actionDelete, f.ProjectID) {
+			msg := forbiddenMessage(actionDelete, f.ProjectID)
+			rec.Result, rec.Error = "denied", msg
+			s.manager.Audit(rec)
+			result.Failed = append(result.Failed, deleteFailure{Name: filename, Error: msg, status: http.StatusForbidden})
+			continue
+		}
+		if !ok {
+			result.Failed = append(result.Failed, deleteFailure{Name: filename, Error: "Файл не найден", status: http.StatusNotFound})
+			continue
+		}
+
+		if err := s.manager.DeleteExportFile(filename); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, export.ErrFileProtected) {
+				status = http.StatusConflict
+			}
+			rec.Result, rec.Error = "error", err.Error()
+			s.manager.Audit(rec)
+			result.Failed = append(result.Failed, deleteFailure{Name: filename, Error: err.Error(), status: status})
+			continue
+		}
+		rec.Result = "ok"
+		s.manager.Audit(rec)
+		result.Deleted = append(result.Deleted, filename)
+	}
+	return result, nil
+}
+
+// auditRecord заполняет запись журнала аудита данными пользователя запроса и файла
+func (s *Server) auditRecord(r *http.Request, action string, f models.ExportFile) models.AuditRecord {
+	rec := models.AuditRecord{
+		Time:       time.Now(),
+		RemoteAddr: r.RemoteAddr,
+		Action:     action,
+		File:       f.Name,
+		ProjectID:  f.ProjectID,
+		Size:       f.Size,
+		SHA256:     f.SHA256,
+	}
+	rec.ForwardedFor = s.forwardedFor(r)
+	if user := currentUser(r); user != nil {
+		rec.User, rec.Method = user.Name, user.Method
+	}
+	return rec
+}
+
+// forwardedFor возвращает X-Forwarded-For запроса, если он пришел от доверенного прокси.
+// Остальным клиентам заголовок не доверяется: его легко подделать
+func (s *Server) forwardedFor(r *http.Request) string {
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !s.config.IsTrustedProxy(ip) {
+		return ""
+	}
+	return forwarded
+}
+
+// handleDelete удаляет выбранные в таблице файлы (POST {"files": [...]})
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkMutation(w, r) {
+		return
+	}
+
+	var body struct {
+		Files []string `json:"files"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Files) == 0 {
+		http.Error(w, "Файлы не выбраны", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.deleteFiles(r, body.Files)
+	if err != nil {
+		http.Error(w, "Ошибка чтения файлов", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"testops-export/pkg/models"
+)
+
+// eventsHeartbeat — как часто отправлять пустой комментарий, чтобы прокси не закрывали поток
+const eventsHeartbeat = 15 * time.Second
+
+// apiRunEvents передает ход запуска экспорта потоком Server-Sent Events:
+// событие progress с состоянием запуска при каждом изменении и done после завершения
+func (s *Server) apiRunEvents(w http.ResponseWriter, r *http.Request, runID string) {
+	run, changed, ok := s.manager.WatchRun(runID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Запуск %s не найден", runID))
+		return
+	}
+	if !s.can(r, actionView, run.ProjectID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", forbiddenMessage(actionView, run.ProjectID))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "streaming_unsupported", "Поток событий не поддерживается")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Отключает буферизацию в nginx
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		writeEvent(w, "progress", run)
+		if run.FinishedAt != nil {
+			writeEvent(w, "done", run)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+	wait:
+		for {
+			select {
+			case <-changed:
+				break wait
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			case <-s.closing:
+				return
+			}
+		}
+		if run, changed, ok = s.manager.WatchRun(runID); !ok {
+			return
+		}
+	}
+}
+
+// writeEvent записывает событие SSE с состоянием запуска в JSON
+func writeEvent(w http.ResponseWriter, event string, run models.RunStatus) {
+	data, _ := json.Marshal(run)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testops-export/pkg/models"
+)
+
+// Размер страницы списка файлов
+const (
+	defaultPerPage = 50
+	maxPerPage     = 1000
+)
+
+// fileQuery — отбор, сортировка и страница списка файлов
+type fileQuery struct {
+	filter  fileFilter
+	sort    string // date, size, name
+	desc    bool
+	page    int // Номер страницы с 1
+	perPage int // 0 — все файлы одной страницей
+}
+
+// parseFileQuery разбирает условия отбора, сортировку (sort, order) и страницу (page, per_page).
+// perPage — размер страницы, если per_page не задан (0 — без разбиения на страницы, пока не задан page)
+func parseFileQuery(q url.Values, perPage int) (fileQuery, error) {
+	filter, err := parseFileFilter(q)
+	if err != nil {
+		return fileQuery{}, err
+	}
+	query := fileQuery{filter: filter, sort: "date", desc: true, page: 1, perPage: perPage}
+
+	switch v := q.Get("sort"); v {
+	case "":
+	case "date", "size", "name":
+		// По имени — по алфавиту, по дате и размеру — сначала новые и большие
+		query.sort, query.desc = v, v != "name"
+	default:
+		return query, fmt.Errorf("некорректный sort: %s (допустимо date, size, name)", v)
+	}
+	switch v := q.Get("order"); v {
+	case "":
+	case "asc", "desc":
+		query.desc = v == "desc"
+	default:
+		return query, fmt.Errorf("некорректный order: %s (допустимо asc, desc)", v)
+	}
+
+	if v := q.Get("page"); v != "" {
+		if query.page, err = strconv.Atoi(v); err != nil || query.page < 1 {
+			return query, fmt.Errorf("некорректный page: %s", v)
+		}
+		if query.perPage == 0 {
+			query.perPage = defaultPerPage
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if query.perPage, err = strconv.Atoi(v); err != nil || query.perPage < 1 || query.perPage > maxPerPage {
+			return query, fmt.Errorf("некорректный per_page: %s (от 1 до %d)", v, maxPerPage)
+		}
+	}
+	return query, nil
+}
+
+// apply отбирает и сортирует файлы, возвращает запрошенную страницу и число подходящих файлов
+func (q fileQuery) apply(files []models.ExportFile) ([]models.ExportFile, int) {
+	matched := files[:0:0]
+	for _, f := range files {
+		if q.filter.match(f) {
+			matched = append(matched, f)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return q.less(matched[i], matched[j]) })
+
+	total := len(matched)
+	if q.perPage == 0 {
+		return matched, total
+	}
+	start := min((q.page-1)*q.perPage, total)
+	end := min(start+q.perPage, total)
+	return matched[start:end], total
+}
+
+func (q fileQuery) less(a, b models.ExportFile) bool {
+	var cmp int
+	switch q.sort {
+	case "size":
+		switch {
+		case a.Size < b.Size:
+			cmp = -1
+		case a.Size > b.Size:
+			cmp = 1
+		}
+	case "name":
+		cmp = strings.Compare(a.Name, b.Name)
+	default:
+		cmp = a.ModifiedTime.Compare(b.ModifiedTime)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.Name, b.Name)
+	}
+	if q.desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// pages возвращает число страниц для total файлов (не меньше одной)
+func (q fileQuery) pages(total int) int {
+	if q.perPage == 0 || total == 0 {
+		return 1
+	}
+	return (total + q.perPage - 1) / q.perPage
+}
+
+// filtered сообщает, задано ли условие отбора помимо проекта
+func (q fileQuery) filtered() bool {
+	f := q.filter
+	return f.group != "" || f.search != "" || f.groupID != 0 || f.runID != "" || f.kind != "" ||
+		f.pinned != nil || !f.since.IsZero() || !f.until.IsZero()
+}
+
+// pageURL возвращает адрес запроса с другой страницей
+func pageURL(u *url.URL, page int) string {
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	return u.Path + "?" + q.Encode()
+}
+
+// newFileList заполняет состояние списка файлов для шаблона главной страницы
+func newFileList(u *url.URL, query fileQuery, total int, groups []string) models.FileList {
+	params := u.Query()
+	list := models.FileList{
+		Group:     params.Get("group"),
+		Search:    params.Get("q"),
+		Since:     params.Get("since"),
+		Until:     params.Get("until"),
+		Sort:      query.sort,
+		Order:     "asc",
+		Page:      query.page,
+		Pages:     query.pages(total),
+		Total:     total,
+		Filtered:  query.filtered(),
+		Groups:    groups,
+		SortURLs:  make(map[string]string),
+		SortMarks: make(map[string]string),
+	}
+	if query.desc {
+		list.Order = "desc"
+	}
+	if list.Page > 1 {
+		list.PrevURL = pageURL(u, list.Page-1)
+	}
+	if list.Page < list.Pages {
+		list.NextURL = pageURL(u, list.Page+1)
+	}
+
+	// Повторный выбор текущей колонки меняет порядок, смена сортировки возвращает на первую страницу
+	for _, column := range []string{"date", "size", "name"} {
+		q := u.Query()
+		q.Del("page")
+		q.Set("sort", column)
+		q.Del("order")
+		if column == query.sort {
+			if query.desc {
+				q.Set("order", "asc")
+				list.SortMarks[column] = "▼"
+			} else {
+				q.Set("order", "desc")
+				list.SortMarks[column] = "▲"
+			}
+		}
+		list.SortURLs[column] = u.Path + "?" + q.Encode()
+	}
+	return list
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"testops-export/pkg/export"
+	"testops-export/pkg/models"
+)
+
+// stageLabels — подписи этапов экспорта группы для страницы групп
+var stageLabels = map[string]string{
+	export.StageQueued:      "⏸ в очереди",
+	export.StageRequesting:  "📨 запрос экспорта",
+	export.StageWaiting:     "⏳ ожидание файла",
+	export.StageDownloading: "📥 скачивание",
+	export.StageSaved:       "✅ сохранен",
+	export.StageRetry:       "🔁 повтор",
+	export.StageFailed:      "❌ ошибка",
+}
+
+// handleGroups показывает сводку по группам экспорта: последний файл, его возраст, последний запуск
+// и устаревшие группы, а также кнопки запуска отдельных групп
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	var selectedProjectID int64
+	if v := r.URL.Query().Get("project_id"); v != "" {
+		fmt.Sscanf(v, "%d", &selectedProjectID)
+		if !s.can(r, actionView, selectedProjectID) {
+			forbid(w, actionView, selectedProjectID)
+			return
+		}
+	}
+
+	groups, err := s.manager.GetGroups(selectedProjectID)
+	if err != nil {
+		log.Printf("Ошибка чтения групп: %v", err)
+		http.Error(w, "Ошибка чтения файлов", http.StatusInternalServerError)
+		return
+	}
+
+	page := models.GroupsPage{SelectedProjectID: selectedProjectID}
+	if s.config.StaleAfter > 0 {
+		page.StaleAfter = export.FormatAge(s.config.StaleAfter)
+	}
+	seen := make(map[int64]bool)
+	for _, g := range groups {
+		if !s.can(r, actionView, g.ProjectID) {
+			continue
+		}
+		g.CanTrigger = s.can(r, actionTrigger, g.ProjectID)
+		g.LatestURL = latestURL(g)
+		if g.Stale {
+			page.StaleCount++
+		}
+		if g.LastRun != nil && g.LastRun.Stage == export.StageFailed {
+			page.FailedCount++
+		}
+		page.Groups = append(page.Groups, g)
+	}
+	for _, p := range s.config.Projects {
+		if p.ProjectID != 0 && !seen[p.ProjectID] && s.can(r, actionView, p.ProjectID) {
+			seen[p.ProjectID] = true
+			page.Projects = append(page.Projects, models.ProjectInfo{ID: p.ProjectID, Name: fmt.Sprintf("%d", p.ProjectID)})
+		}
+	}
+	s.renderPage(w, "groups", page)
+}
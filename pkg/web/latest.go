@@ -0,0 +1,148 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"testops-export/pkg/export"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// handleLatest отдает последний успешный экспорт группы по постоянному адресу:
+// /latest/{проект}/{группа}.csv — содержимое CSV, /latest/{проект}/{группа}.json — сведения о файле.
+// Группа задается именем или ID. ETag и Last-Modified позволяют опрашивать адрес без повторной загрузки
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/latest/"), "/")
+	if len(parts) != 2 {
+		http.Error(w, "Ожидается /latest/{проект}/{группа}.csv или .json", http.StatusNotFound)
+		return
+	}
+	projectID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || projectID <= 0 {
+		http.Error(w, "Некорректный ID проекта: "+parts[0], http.StatusBadRequest)
+		return
+	}
+	ext := path.Ext(parts[1])
+	group := strings.TrimSuffix(parts[1], ext)
+	if (ext != ".csv" && ext != ".json") || group == "" {
+		http.Error(w, "Ожидается /latest/{проект}/{группа}.csv или .json", http.StatusNotFound)
+		return
+	}
+
+	// Для сведений о файле достаточно просмотра, для содержимого нужно право на скачивание
+	action := actionDownload
+	if ext == ".json" {
+		action = actionView
+	}
+	if !s.can(r, action, projectID) {
+		forbid(w, action, projectID)
+		return
+	}
+
+	f, err := s.manager.LatestGroupFile(projectID, group)
+	if errors.Is(err, export.ErrFileNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка поиска последнего экспорта %d/%s: %v", projectID, group, err)
+		http.Error(w, "Ошибка чтения файлов", http.StatusInternalServerError)
+		return
+	}
+
+	if ext == ".json" {
+		body, _ := json.Marshal(toAPIFile(f))
+		if writeValidators(w, r, fmt.Sprintf(`"%x"`, sha256.Sum256(body)), f.ModifiedTime) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+		return
+	}
+
+	// ETag — контрольная сумма из метаданных. Файлы экспорта не меняются после сохранения,
+	// поэтому для файлов без метаданных достаточно имени и размера
+	etag := fmt.Sprintf(`"%s"`, f.SHA256)
+	if f.SHA256 == "" {
+		etag = fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(fmt.Sprintf("%s:%d", f.Name, f.Size))))
+	}
+	if writeValidators(w, r, etag, f.ModifiedTime) {
+		return
+	}
+	servedName := storage.UncompressedName(f.Name)
+	setFileHeaders := func() {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": servedName}))
+		w.Header().Set("Content-Type", storage.ContentType(servedName))
+		w.Header().Set("X-Export-File", f.Name)
+	}
+
+	// HEAD отвечает по метаданным, не читая файл. Размер распакованного файла заранее неизвестен,
+	// поэтому Content-Length отдается только для несжатых файлов
+	if r.Method == http.MethodHead {
+		setFileHeaders()
+		if f.Compression == storage.CompressionNone {
+			w.Header().Set("Content-Length", strconv.FormatInt(f.Size, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	data, err := s.manager.DownloadDecompressedExportFile(f.Name)
+	if err != nil {
+		http.Error(w, "Файл не найден", http.StatusNotFound)
+		return
+	}
+	setFileHeaders()
+	http.ServeContent(w, r, servedName, f.ModifiedTime, bytes.NewReader(data))
+}
+
+// writeValidators выставляет ETag и Last-Modified и отвечает 304, если у клиента актуальная версия.
+// If-None-Match проверяется первым, If-Modified-Since — только без него
+func writeValidators(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	notModified := false
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				notModified = true
+				break
+			}
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		notModified = !modified.Truncate(time.Second).After(since)
+	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// latestURL возвращает постоянный адрес последнего экспорта группы: по имени, а если имя
+// не годится для пути — по ID
+func latestURL(g models.GroupOverview) string {
+	group := g.GroupName
+	if group == "" || strings.Contains(group, "/") {
+		group = strconv.Itoa(g.GroupID)
+	}
+	return fmt.Sprintf("/latest/%d/%s.csv", g.ProjectID, url.PathEscape(group))
+}
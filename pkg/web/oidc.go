@@ -0,0 +1,198 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"testops-export/pkg/config"
+	"testops-export/pkg/models"
+)
+
+// oidcStateCookie хранит state, nonce и адрес возврата на время входа через провайдера
+const oidcStateCookie = "testops_oidc"
+
+// oidcLoginTTL — сколько ждать возврата пользователя от провайдера
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcAuth выполняет вход пользователей через OIDC провайдера (authorization code flow)
+type oidcAuth struct {
+	cfg     *config.Config
+	session *sessionStore
+
+	// Провайдер подключается при первом входе, чтобы сервис запускался и без доступа к нему
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+	oauth2   *oauth2.Config
+}
+
+// oidcLogin — содержимое cookie входа
+type oidcLogin struct {
+	State   string `json:"state"`
+	Nonce   string `json:"nonce"`
+	Next    string `json:"next"`
+	Expires int64  `json:"exp"`
+}
+
+func newOIDCAuth(cfg *config.Config, session *sessionStore) *oidcAuth {
+	return &oidcAuth{cfg: cfg, session: session}
+}
+
+// provider возвращает настройки OAuth2 и проверку ID токена, при необходимости читая discovery документ провайдера
+func (o *oidcAuth) provider() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.verifier != nil {
+		return o.oauth2, o.verifier, nil
+	}
+
+	// Контекст запроса не подходит: через него провайдер позже загружает ключи подписи
+	provider, err := oidc.NewProvider(context.Background(), o.cfg.OIDCIssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка подключения к OIDC провайдеру %s: %v", o.cfg.OIDCIssuerURL, err)
+	}
+	o.oauth2 = &oauth2.Config{
+		ClientID:     o.cfg.OIDCClientID,
+		ClientSecret: o.cfg.OIDCClientSecret,
+		RedirectURL:  o.cfg.OIDCRedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       o.cfg.OIDCScopes,
+	}
+	o.verifier = provider.Verifier(&oidc.Config{ClientID: o.cfg.OIDCClientID})
+	return o.oauth2, o.verifier, nil
+}
+
+// handleLogin перенаправляет пользователя на страницу входа провайдера
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	o := s.auth.oidc
+	if o == nil {
+		http.NotFound(w, r)
+		return
+	}
+	oauthCfg, _, err := o.provider()
+	if err != nil {
+		log.Printf("%v", err)
+		http.Error(w, "OIDC провайдер недоступен", http.StatusBadGateway)
+		return
+	}
+
+	login := oidcLogin{
+		State:   randomString(),
+		Nonce:   randomString(),
+		Next:    safeRedirect(r.URL.Query().Get("next")),
+		Expires: time.Now().Add(oidcLoginTTL).Unix(),
+	}
+	setCookie(w, r, oidcStateCookie, o.session.encode(oidcStateCookie, login), time.Now().Add(oidcLoginTTL))
+	http.Redirect(w, r, oauthCfg.AuthCodeURL(login.State, oidc.Nonce(login.Nonce)), http.StatusFound)
+}
+
+// handleCallback принимает пользователя от провайдера, проверяет ID токен и начинает сессию
+func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	o := s.auth.oidc
+	if o == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if msg := r.URL.Query().Get("error"); msg != "" {
+		http.Error(w, "Вход отклонен провайдером: "+msg+" "+r.URL.Query().Get("error_description"), http.StatusUnauthorized)
+		return
+	}
+
+	var login oidcLogin
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || !o.session.decode(oidcStateCookie, cookie.Value, &login) || time.Now().Unix() > login.Expires ||
+		r.URL.Query().Get("state") != login.State {
+		http.Error(w, "Сеанс входа истек, попробуйте еще раз", http.StatusBadRequest)
+		return
+	}
+	setCookie(w, r, oidcStateCookie, "", time.Unix(0, 0))
+
+	oauthCfg, verifier, err := o.provider()
+	if err != nil {
+		log.Printf("%v", err)
+		http.Error(w, "OIDC провайдер недоступен", http.StatusBadGateway)
+		return
+	}
+	token, err := oauthCfg.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		log.Printf("Ошибка обмена кода OIDC: %v", err)
+		http.Error(w, "Ошибка входа", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "Провайдер не вернул ID токен", http.StatusUnauthorized)
+		return
+	}
+	idToken, err := verifier.Verify(r.Context(), rawIDToken)
+	if err != nil || idToken.Nonce != login.Nonce {
+		log.Printf("Ошибка проверки ID токена: %v", err)
+		http.Error(w, "Ошибка входа", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := o.userFromToken(idToken)
+	if err != nil {
+		log.Printf("Ошибка чтения ID токена: %v", err)
+		http.Error(w, "Ошибка входа", http.StatusUnauthorized)
+		return
+	}
+	log.Printf("Вход через OIDC: %s", user.Name)
+	o.session.start(w, r, user)
+	http.Redirect(w, r, login.Next, http.StatusFound)
+}
+
+// userFromToken заполняет пользователя по claims ID токена
+func (o *oidcAuth) userFromToken(idToken *oidc.IDToken) (models.User, error) {
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{Method: "oidc"}
+	user.Email, _ = claims["email"].(string)
+	user.Name, _ = claims["preferred_username"].(string)
+	if user.Name == "" {
+		user.Name = user.Email
+	}
+	if user.Name == "" {
+		user.Name = idToken.Subject
+	}
+
+	// Группы приходят списком или, у некоторых провайдеров, одной строкой
+	switch groups := claims[o.cfg.OIDCGroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if name, ok := g.(string); ok {
+				user.Groups = append(user.Groups, name)
+			}
+		}
+	case string:
+		user.Groups = []string{groups}
+	}
+	return user, nil
+}
+
+// safeRedirect оставляет только локальные адреса возврата, чтобы вход нельзя было использовать для редиректа на чужой сайт
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// randomString возвращает случайную строку для state и nonce
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"testops-export/pkg/export"
+	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
+)
+
+// previewPerPage — строк на странице просмотра CSV
+const previewPerPage = 25
+
+// handlePreview показывает CSV экспорта таблицей: поиск (q, in — колонка), выбор колонок (col) и страницы (page)
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	filename := strings.TrimPrefix(r.URL.Path, "/preview/")
+	if filename == "" {
+		http.Error(w, "Имя файла не указано", http.StatusBadRequest)
+		return
+	}
+	if !isSafeFilename(filename) {
+		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		return
+	}
+	if storage.IsBundle(filename) || storage.IsManifest(filename) {
+		http.Error(w, "Просмотр доступен только для CSV экспортов", http.StatusBadRequest)
+		return
+	}
+	if !s.canAccessFile(r, actionDownload, filename) {
+		http.Error(w, "Нет прав на просмотр файла", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	page := 1
+	if v := q.Get("page"); v != "" {
+		var err error
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			http.Error(w, "Некорректный номер страницы: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := s.manager.DownloadDecompressedExportFile(filename)
+	if err != nil {
+		http.Error(w, "Файл не найден", http.StatusNotFound)
+		return
+	}
+	header, rows, err := export.ParseCSV(data)
+	if err != nil {
+		log.Printf("Ошибка просмотра %s: %v", filename, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Выбранные колонки; без col показываются все
+	selected := make(map[string]bool)
+	for _, name := range q["col"] {
+		selected[name] = true
+	}
+	var columns []models.PreviewColumn
+	var shown []int
+	for i, name := range header {
+		col := models.PreviewColumn{Name: name, Selected: len(selected) == 0 || selected[name]}
+		if col.Selected {
+			shown = append(shown, i)
+		}
+		columns = append(columns, col)
+	}
+
+	search := strings.TrimSpace(q.Get("q"))
+	searchIn := -1
+	for i, name := range header {
+		if name == q.Get("in") {
+			searchIn = i
+			break
+		}
+	}
+	var matched [][]string
+	for _, row := range rows {
+		if rowMatches(row, strings.ToLower(search), searchIn) {
+			matched = append(matched, row)
+		}
+	}
+
+	preview := models.PreviewPage{
+		Filename:  filename,
+		Columns:   columns,
+		Search:    search,
+		Page:      page,
+		Pages:     max(1, (len(matched)+previewPerPage-1)/previewPerPage),
+		Total:     len(matched),
+		TotalRows: len(rows),
+	}
+	if searchIn >= 0 {
+		preview.SearchIn = header[searchIn]
+	}
+	for _, i := range shown {
+		preview.Header = append(preview.Header, header[i])
+	}
+	start := min((page-1)*previewPerPage, len(matched))
+	end := min(start+previewPerPage, len(matched))
+	for _, row := range matched[start:end] {
+		cells := make([]string, 0, len(shown))
+		for _, i := range shown {
+			cells = append(cells, row[i])
+		}
+		preview.Rows = append(preview.Rows, cells)
+	}
+	if page > 1 {
+		preview.PrevURL = pageURL(r.URL, page-1)
+	}
+	if page < preview.Pages {
+		preview.NextURL = pageURL(r.URL, page+1)
+	}
+
+	s.renderPage(w, "preview", preview)
+}
+
+// rowMatches ищет подстроку (в нижнем регистре) в колонке column или, если column < 0, во всех колонках
+func rowMatches(row []string, search string, column int) bool {
+	if search == "" {
+		return true
+	}
+	for i, value := range row {
+		if (column < 0 || i == column) && strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,23 +3,29 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"mime"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"testops-export/pkg/config"
 	"testops-export/pkg/export"
 	"testops-export/pkg/models"
+	"testops-export/pkg/storage"
 )
 
 // Server представляет веб-сервер
 type Server struct {
 	config  *config.Config
 	manager *export.Manager
+	auth    *authenticator
 	httpSrv *http.Server
+	closing chan struct{} // Закрывается при остановке сервера, чтобы завершить потоки событий
 }
 
 // NewServer создает новый веб-сервер
@@ -27,6 +33,8 @@ func NewServer(manager *export.Manager) *Server {
 	return &Server{
 		config:  manager.Config(),
 		manager: manager,
+		auth:    newAuthenticator(manager.Config()),
+		closing: make(chan struct{}),
 	}
 }
 
@@ -36,10 +44,32 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/export", s.handleExport)
 	mux.HandleFunc("/download/", s.handleDownload)
+	mux.HandleFunc("/preview/", s.handlePreview)
+	mux.HandleFunc("/download-zip", s.handleDownloadZip)
+	mux.HandleFunc("/verify/", s.handleVerify)
+	mux.HandleFunc("/pin", s.handlePin)
+	mux.HandleFunc("/snapshots", s.handleSnapshots)
+	mux.HandleFunc("/groups", s.handleGroups)
+	mux.HandleFunc("/latest/", s.handleLatest)
+	mux.HandleFunc("/scrub", s.handleScrub)
+	mux.HandleFunc("/snapshots/", s.handleSnapshotDownload)
+	mux.HandleFunc("/unpin", s.handlePin)
+	mux.HandleFunc("/delete", s.handleDelete)
+	mux.HandleFunc(apiPrefix, s.handleAPI)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/auth/login", s.handleLogin)
+	mux.HandleFunc("/auth/callback", s.handleCallback)
+	mux.HandleFunc("/auth/logout", s.handleLogout)
 
 	s.httpSrv = &http.Server{
 		Addr:    ":" + s.config.WebPort,
-		Handler: mux,
+		Handler: s.auth.middleware(mux),
+	}
+	// Shutdown не прерывает открытые запросы — потоки событий завершаются сами
+	s.httpSrv.RegisterOnShutdown(func() { close(s.closing) })
+
+	if s.auth.enabled() {
+		log.Printf("Аутентификация включена: %s", strings.Join(s.config.AuthMethods, ", "))
 	}
 
 	log.Printf("Веб-сервер запущен на порту %s", s.config.WebPort)
@@ -59,28 +89,52 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // handleIndex обрабатывает главную страницу
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	// Получаем project_id из query
-	var files []models.ExportFile
-	var err error
 	projectIDStr := r.URL.Query().Get("project_id")
 	var selectedProjectID int64
 	if projectIDStr != "" {
 		fmt.Sscanf(projectIDStr, "%d", &selectedProjectID)
-		files, err = s.manager.GetExportFiles(selectedProjectID)
-	} else {
-		files, err = s.manager.GetExportFiles()
+		if !s.can(r, actionView, selectedProjectID) {
+			forbid(w, actionView, selectedProjectID)
+			return
+		}
 	}
+	// Файлы и занятое место — по одному списку хранилища; метаданные из него перечитываются,
+	// только если изменились, поэтому страница не обращается к хранилищу за каждым файлом
+	files, usage, err := s.manager.GetExportFilesWithUsage(selectedProjectID)
 	if err != nil {
 		http.Error(w, "Ошибка чтения файлов", http.StatusInternalServerError)
 		return
 	}
+	files = s.visibleFiles(r, files)
+
+	// Отбор, сортировка и страницы списка выполняются на сервере: в страницу попадает только текущая страница файлов
+	query, err := parseFileQuery(r.URL.Query(), defaultPerPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageFiles, matched := query.apply(files)
 
-	// Собираем список всех проектов из конфига (Projects), чтобы показывать даже те, по которым ещё нет экспортов
+	// Собираем список всех проектов из конфига (Projects), чтобы показывать даже те, по которым ещё нет экспортов.
+	// Проекты без прав на просмотр не показываются
 	projectMap := make(map[int64]struct{})
+	groupMap := make(map[string]struct{})
 	for _, p := range s.config.Projects {
-		if p.ProjectID != 0 {
+		if p.ProjectID != 0 && s.can(r, actionView, p.ProjectID) {
 			projectMap[p.ProjectID] = struct{}{}
+			if selectedProjectID == 0 || p.ProjectID == selectedProjectID {
+				for _, g := range p.Groups {
+					groupMap[g.GroupName] = struct{}{}
+				}
+			}
 		}
 	}
+	groups := make([]string, 0, len(groupMap))
+	for name := range groupMap {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
 	var projects []models.ProjectInfo
 	for id := range projectMap {
 		projects = append(projects, models.ProjectInfo{ID: id, Name: fmt.Sprintf("%d", id)})
@@ -111,8 +165,14 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		ErrorMessage:     nextExportInfo.ErrorMessage,
 	}
 
+	// Общее занятое место показывается только тем, кто видит все проекты
+	if !s.can(r, actionView, selectedProjectID) {
+		usage = models.StorageUsage{}
+	}
+
 	data := models.PageData{
-		Files:             files,
+		Files:             pageFiles,
+		List:              newFileList(r.URL, query, matched, groups),
 		TotalFiles:        fmt.Sprintf("%d", len(files)),
 		TotalSize:         s.manager.FormatFileSize(totalSize),
 		LastExport:        lastExport,
@@ -120,8 +180,18 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		SelectedProjectID: selectedProjectID,
 		CronSchedule:      s.config.CronSchedule,
 		NextExport:        nextExport,
+		Usage:             usage,
+		Scrub:             s.visibleScrubReport(r),
+		User:              currentUser(r),
+		CanTrigger:        selectedProjectID != 0 && s.can(r, actionTrigger, selectedProjectID),
 	}
 
+	s.renderPage(w, "index", data)
+}
+
+// renderPage отрисовывает страницу веб-интерфейса. Все страницы разбираются одним набором,
+// чтобы использовать общие стили из главной страницы
+func (s *Server) renderPage(w http.ResponseWriter, name string, data interface{}) {
 	tmpl, err := template.New("index").Funcs(template.FuncMap{
 		"toJson": func(v interface{}) template.JS {
 			b, _ := json.Marshal(v)
@@ -130,41 +200,247 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		"formatCronSchedule": func(cronExpr string) string {
 			return formatCronSchedule(cronExpr)
 		},
+		"stageLabel": func(stage string) string {
+			if label, ok := stageLabels[stage]; ok {
+				return label
+			}
+			return stage
+		},
 	}).Parse(htmlTemplate)
+	if err == nil {
+		_, err = tmpl.New("snapshots").Parse(snapshotsTemplate)
+	}
+	if err == nil {
+		_, err = tmpl.New("preview").Parse(previewTemplate)
+	}
+	if err == nil {
+		_, err = tmpl.New("groups").Parse(groupsTemplate)
+	}
 	if err != nil {
 		http.Error(w, "Ошибка шаблона", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	tmpl.Execute(w, data)
+	tmpl.ExecuteTemplate(w, name, data)
 }
 
-// handleExport обрабатывает запрос на экспорт
+// handleExport обрабатывает запрос на экспорт проекта или его отдельных групп
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
+	if !checkMutation(w, r) {
+		return
+	}
 
 	type req struct {
 		ProjectID int64 `json:"project_id"`
+		GroupID   int   `json:"group_id"`  // Одна группа проекта
+		GroupIDs  []int `json:"group_ids"` // Несколько групп проекта
 	}
 	var body req
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
 		return
 	}
+	if !s.can(r, actionTrigger, body.ProjectID) {
+		forbid(w, actionTrigger, body.ProjectID)
+		return
+	}
+	if body.GroupID != 0 {
+		body.GroupIDs = append(body.GroupIDs, body.GroupID)
+	}
+	if len(body.GroupIDs) > 0 && body.ProjectID == 0 {
+		http.Error(w, "Для экспорта отдельных групп укажите проект", http.StatusBadRequest)
+		return
+	}
 
-	if body.ProjectID == 0 {
-		go s.manager.PerformExportParallel()
-	} else {
-		go s.manager.PerformExportForProjectParallel(body.ProjectID)
+	runID, err := s.manager.StartExport(body.ProjectID, body.GroupIDs...)
+	if errors.Is(err, export.ErrRunExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "Экспорт запущен"})
+	json.NewEncoder(w).Encode(map[string]string{"status": "Экспорт запущен", "run_id": runID})
+}
+
+// handlePin закрепляет (/pin) или открепляет (/unpin) файл или все файлы запуска
+func (s *Server) handlePin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkMutation(w, r) {
+		return
+	}
+
+	type req struct {
+		Filename string `json:"filename"`
+		RunID    string `json:"run_id"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	pinned := r.URL.Path == "/pin"
+	var changed []string
+	var err error
+	switch {
+	case body.Filename != "":
+		if !isSafeFilename(body.Filename) {
+			http.Error(w, "Доступ запрещен", http.StatusForbidden)
+			return
+		}
+		if projectID := s.manager.FileProjectID(body.Filename); !s.can(r, actionDelete, projectID) {
+			forbid(w, actionDelete, projectID)
+			return
+		}
+		changed, err = s.manager.SetPinned(body.Filename, pinned)
+	case body.RunID != "":
+		// Закрепление управляет удалением файлов, поэтому нужны права delete на все проекты запуска
+		files, listErr := s.manager.GetExportFiles()
+		if listErr != nil {
+			http.Error(w, "Ошибка чтения файлов", http.StatusInternalServerError)
+			return
+		}
+		for _, f := range files {
+			if f.RunID == body.RunID && !s.can(r, actionDelete, f.ProjectID) {
+				forbid(w, actionDelete, f.ProjectID)
+				return
+			}
+		}
+		changed, err = s.manager.SetRunPinned(body.RunID, pinned)
+	default:
+		http.Error(w, "Укажите filename или run_id", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"pinned": pinned, "files": changed})
+}
+
+// handleScrub возвращает отчет последней проверки целостности (GET) или запускает проверку (POST)
+func (s *Server) handleScrub(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.visibleScrubReport(r))
+	case http.MethodPost:
+		if !checkMutation(w, r) {
+			return
+		}
+		if !s.canAdmin(r) {
+			forbid(w, actionAdmin, 0)
+			return
+		}
+		go s.manager.Scrub()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "Проверка запущена"})
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSnapshots показывает снимки релизов (GET) или создает снимок (POST)
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := s.manager.GetSnapshots()
+		if err != nil {
+			http.Error(w, "Ошибка чтения снимков", http.StatusInternalServerError)
+			return
+		}
+		// Показываем только файлы доступных проектов
+		visible := snapshots[:0]
+		for _, snap := range snapshots {
+			if snap.Files = s.visibleFiles(r, snap.Files); len(snap.Files) > 0 {
+				visible = append(visible, snap)
+			}
+		}
+		s.renderPage(w, "snapshots", visible)
+	case http.MethodPost:
+		if !checkMutation(w, r) {
+			return
+		}
+		if !s.canAdmin(r) {
+			forbid(w, actionAdmin, 0)
+			return
+		}
+		type req struct {
+			Label string   `json:"label"`
+			Files []string `json:"files"` // Пусто — последний экспорт каждой группы
+		}
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+			return
+		}
+		for _, filename := range body.Files {
+			if !isSafeFilename(filename) {
+				http.Error(w, "Доступ запрещен", http.StatusForbidden)
+				return
+			}
+		}
+
+		snapshot, err := s.manager.CreateSnapshot(body.Label, body.Files)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSnapshotDownload отдает снимок релиза одним ZIP архивом (/snapshots/<метка>.zip)
+func (s *Server) handleSnapshotDownload(w http.ResponseWriter, r *http.Request) {
+	label := strings.TrimSuffix(r.URL.Path[len("/snapshots/"):], ".zip")
+	if !isSafeFilename(label) {
+		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		return
+	}
+
+	snapshot, err := s.manager.GetSnapshot(label)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var filenames []string
+	for _, f := range snapshot.Files {
+		if !s.can(r, actionDownload, f.ProjectID) {
+			forbid(w, actionDownload, f.ProjectID)
+			return
+		}
+		filenames = append(filenames, f.Name)
+	}
+	files, err := s.manager.ZipSources(filenames)
+	if err != nil {
+		log.Printf("Ошибка сборки архива снимка %s: %v", label, err)
+		http.Error(w, "Ошибка сборки архива", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "testops_snapshot_" + label + ".zip"}))
+	w.Header().Set("Content-Type", "application/zip")
+	if err := s.manager.WriteZip(w, files); err != nil {
+		log.Printf("Ошибка записи архива снимка %s: %v", label, err)
+	}
 }
 
 // handleDownload обрабатывает скачивание файлов
@@ -176,22 +452,124 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверяем, что имя файла не содержит подозрительные символы
-	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
+	if !isSafeFilename(filename) {
 		http.Error(w, "Доступ запрещен", http.StatusForbidden)
 		return
 	}
 
-	data, err := s.manager.DownloadExportFile(filename)
+	if !s.canAccessFile(r, actionDownload, filename) {
+		http.Error(w, "Нет прав на скачивание файла", http.StatusForbidden)
+		return
+	}
+
+	// Сжатые файлы распаковываются, если клиент не запросил сжатый вид (?raw=1)
+	decompress := storage.CompressionFromName(filename) != storage.CompressionNone && r.URL.Query().Get("raw") == ""
+
+	// Перенаправляем на временную ссылку хранилища, если это разрешено.
+	// Параметр ?proxy=1 принудительно отдает файл через сервер (бакет недоступен из браузера)
+	if !decompress && r.URL.Query().Get("proxy") == "" {
+		if url, ok := s.manager.PresignedDownloadURL(filename); ok {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+	}
+
+	var data []byte
+	var err error
+	servedName := filename
+	if decompress {
+		data, err = s.manager.DownloadDecompressedExportFile(filename)
+		servedName = storage.UncompressedName(filename)
+	} else {
+		data, err = s.manager.DownloadExportFile(filename)
+	}
 	if err != nil {
 		http.Error(w, "Файл не найден", http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": servedName}))
+	w.Header().Set("Content-Type", storage.ContentType(servedName))
 	w.Write(data)
 }
 
+// handleDownloadZip отдает ZIP архив из выбранных файлов (параметры files=...)
+func (s *Server) handleDownloadZip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Неверный запрос", http.StatusBadRequest)
+		return
+	}
+
+	filenames := r.Form["files"]
+	if len(filenames) == 0 {
+		http.Error(w, "Файлы не выбраны", http.StatusBadRequest)
+		return
+	}
+	if len(filenames) > export.MaxZipFiles {
+		http.Error(w, fmt.Sprintf("Можно выбрать не больше %d файлов", export.MaxZipFiles), http.StatusBadRequest)
+		return
+	}
+	for _, filename := range filenames {
+		if !isSafeFilename(filename) {
+			http.Error(w, "Доступ запрещен", http.StatusForbidden)
+			return
+		}
+		if !s.canAccessFile(r, actionDownload, filename) {
+			http.Error(w, "Нет прав на скачивание файла "+filename, http.StatusForbidden)
+			return
+		}
+	}
+
+	files, err := s.manager.ZipSources(filenames)
+	if err != nil {
+		log.Printf("Ошибка сборки архива: %v", err)
+		http.Error(w, "Ошибка сборки архива: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	zipName := fmt.Sprintf("testops_exports_%s.zip", time.Now().Format("2006-01-02_15-04-05"))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": zipName}))
+	w.Header().Set("Content-Type", "application/zip")
+	if err := s.manager.WriteZip(w, files); err != nil {
+		log.Printf("Ошибка записи архива: %v", err)
+	}
+}
+
+// handleVerify проверяет файлы запуска по манифесту и возвращает отчет в JSON
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	filename := r.URL.Path[len("/verify/"):]
+	if !isSafeFilename(filename) {
+		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		return
+	}
+
+	if !s.canAccessFile(r, actionView, filename) {
+		http.Error(w, "Нет прав на просмотр манифеста", http.StatusForbidden)
+		return
+	}
+
+	report, err := s.manager.VerifyManifest(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.OK {
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// isSafeFilename проверяет, что имя файла не содержит подозрительные символы
+func isSafeFilename(filename string) bool {
+	return filename != "" && !strings.Contains(filename, "..") && !strings.Contains(filename, "/") && !strings.Contains(filename, "\\")
+}
+
 // formatCronSchedule преобразует cron выражение в понятное описание
 func formatCronSchedule(cronExpr string) string {
 	parts := strings.Fields(cronExpr)
@@ -218,7 +596,7 @@ const htmlTemplate = `
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>TestOps Export Manager</title>
     <link rel="icon" href="data:image/svg+xml,<svg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 100 100'><text y='.9em' font-size='90'>📊</text></svg>">
-    <style>
+    {{block "styles" .}}<style>
         body {
             font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
             margin: 0;
@@ -249,6 +627,18 @@ const htmlTemplate = `
             opacity: 0.9;
             font-size: 1.1em;
         }
+        .header .header-user {
+            font-size: 0.95em;
+        }
+        .btn-logout {
+            background: transparent;
+            color: white;
+            border: 1px solid rgba(255,255,255,0.7);
+            border-radius: 4px;
+            padding: 2px 10px;
+            margin-left: 8px;
+            cursor: pointer;
+        }
         .content {
             padding: 30px;
         }
@@ -300,6 +690,12 @@ const htmlTemplate = `
         .btn-secondary:hover {
             background: #5a6268;
         }
+        .btn-danger {
+            background: #dc3545;
+        }
+        .btn-danger:hover {
+            background: #c82333;
+        }
         .btn:disabled {
             background: #c0c4cc;
             color: #f8f9fa;
@@ -333,6 +729,64 @@ const htmlTemplate = `
         .download-link:hover {
             text-decoration: underline;
         }
+        .delete-link {
+            color: #dc3545;
+        }
+        .filters {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 10px;
+            align-items: center;
+            margin-bottom: 15px;
+            color: #6c757d;
+        }
+        .filter-input {
+            padding: 8px 10px;
+            border: 1px solid #ced4da;
+            border-radius: 6px;
+            font-size: 14px;
+        }
+        .sort-link {
+            color: inherit;
+            text-decoration: none;
+        }
+        .progress-panel {
+            margin-bottom: 20px;
+        }
+        .progress-summary {
+            margin-bottom: 10px;
+            color: #495057;
+            font-weight: 500;
+        }
+        .stage-saved td {
+            color: #28a745;
+        }
+        .stage-failed td, .stage-retry td {
+            color: #dc3545;
+        }
+        .pager {
+            display: flex;
+            justify-content: center;
+            gap: 15px;
+            margin: 15px 0;
+            color: #6c757d;
+        }
+        .badge {
+            display: inline-block;
+            margin-left: 6px;
+            padding: 1px 6px;
+            border-radius: 4px;
+            background: #667eea;
+            color: white;
+            font-size: 11px;
+            font-weight: 600;
+        }
+        .badge-pin {
+            background: #ffc107;
+        }
+        .badge-corrupt {
+            background: #dc3545;
+        }
         .empty-state {
             text-align: center;
             padding: 60px 20px;
@@ -395,13 +849,18 @@ const htmlTemplate = `
                 padding: 8px;
             }
         }
-    </style>
+    </style>{{end}}
 </head>
 <body>
     <div class="container">
         <div class="header">
             <h1>TestOps Export Manager</h1>
             <p>Управление экспортами тесткейсов</p>
+            {{with .User}}
+            <p class="header-user">👤 {{.Name}}
+                {{if eq .Method "oidc"}}<form method="POST" action="/auth/logout" style="display:inline;"><button type="submit" class="btn-logout">Выйти</button></form>{{end}}
+            </p>
+            {{end}}
         </div>
         
         <div class="content">
@@ -414,6 +873,12 @@ const htmlTemplate = `
                     <div class="stat-number">{{.TotalSize}}</div>
                     <div class="stat-label">Общий размер</div>
                 </div>
+                {{if .Usage.Quota}}
+                <div class="stat-card">
+                    <div class="stat-number" {{if ge .Usage.Percent 90}}style="color:#dc3545;"{{end}}>{{.Usage.FormattedUsed}} / {{.Usage.FormattedQuota}}</div>
+                    <div class="stat-label">{{if .SelectedProjectID}}Квота проекта{{else}}Квота хранилища{{end}} ({{.Usage.Percent}}%)</div>
+                </div>
+                {{end}}
                 <div class="stat-card">
                     <div class="stat-number">{{.LastExport}}</div>
                     <div class="stat-label">Последний экспорт</div>
@@ -434,11 +899,79 @@ const htmlTemplate = `
                         {{end}}
                     </select>
                 </form>
-                <button id="exportBtn" class="btn" {{if eq .SelectedProjectID 0}}disabled{{end}}>Запустить экспорт сейчас</button>
+                <button id="exportBtn" class="btn" {{if not .CanTrigger}}disabled{{end}}>Запустить экспорт сейчас</button>
                 <button type="button" class="btn btn-secondary" onclick="location.reload();">Обновить</button>
+                <button type="button" id="snapshotBtn" class="btn btn-secondary">Создать снимок релиза</button>
+                <a href="/snapshots" class="download-link">Снимки релизов</a>
+                <a href="/groups{{if .SelectedProjectID}}?project_id={{.SelectedProjectID}}{{end}}" class="download-link">Группы</a>
             </div>
 
+            {{/* Ход запуска экспорта: общий для главной страницы и страницы групп */}}
+            {{block "progress" .}}
             <div id="exportStatus" style="text-align:center; margin-bottom:20px; color:#28a745; display:none;"></div>
+            <div id="progressPanel" class="progress-panel" style="display:none;">
+                <div id="progressSummary" class="progress-summary"></div>
+                <table class="exports-table">
+                    <tbody id="progressTbody"></tbody>
+                </table>
+            </div>
+            <script>
+// Этапы экспорта группы
+const stageLabels = {
+    queued: '⏸ в очереди',
+    requesting: '📨 запрос экспорта',
+    waiting: '⏳ ожидание файла',
+    downloading: '📥 скачивание',
+    saved: '✅ сохранен',
+    retry: '🔁 повтор',
+    failed: '❌ ошибка'
+};
+
+// watchRun показывает ход запуска из потока событий и по завершении обновляет страницу
+function watchRun(runId, btn) {
+    const panel = document.getElementById('progressPanel');
+    panel.style.display = 'block';
+    document.getElementById('exportStatus').style.display = 'none';
+    const events = new EventSource('/api/v1/runs/' + encodeURIComponent(runId) + '/events');
+    events.addEventListener('progress', e => renderProgress(JSON.parse(e.data)));
+    events.addEventListener('done', e => {
+        events.close();
+        const run = JSON.parse(e.data);
+        renderProgress(run);
+        document.getElementById('progressSummary').textContent +=
+            ' Завершен: ' + run.succeeded + ' из ' + run.total + ' групп. Страница обновится через несколько секунд.';
+        setTimeout(() => location.reload(), 3000);
+    });
+    events.onerror = function() {
+        events.close();
+        btn.disabled = false;
+        document.getElementById('progressSummary').textContent =
+            'Связь с сервером потеряна. Экспорт продолжается, обновите страницу позже.';
+    };
+}
+
+function renderProgress(run) {
+    const done = run.succeeded + run.failed;
+    document.getElementById('progressSummary').textContent =
+        'Запуск ' + run.run_id + ': готово ' + done + ' из ' + run.total + ' групп.';
+    const tbody = document.getElementById('progressTbody');
+    tbody.innerHTML = '';
+    for (const g of run.groups) {
+        let stage = stageLabels[g.stage] || g.stage;
+        if (g.stage === 'retry') stage += ' (попытка ' + g.attempt + ' из ' + g.attempts + ')';
+        else if (g.attempt > 1 && g.stage !== 'saved' && g.stage !== 'failed') stage += ' (попытка ' + g.attempt + ')';
+        const row = document.createElement('tr');
+        row.className = 'stage-' + g.stage;
+        [g.project_id, g.group_name, stage, g.file || g.error || ''].forEach(text => {
+            const td = document.createElement('td');
+            td.textContent = text;
+            row.appendChild(td);
+        });
+        tbody.appendChild(row);
+    }
+}
+            </script>
+            {{end}}
 
             <div style="text-align:center; margin-bottom:20px; color:#6c757d; font-size:0.9em;">
                 <p>⏰ Автоматический экспорт выполняется {{formatCronSchedule .CronSchedule}}</p>
@@ -447,15 +980,38 @@ const htmlTemplate = `
                 {{else}}
                 <p style="color:#28a745;">⏳ Следующий экспорт через <strong>{{.NextExport.FormattedTime}}</strong> ({{.NextExport.NextRunFormatted}} UTC)</p>
                 {{end}}
+                <p>🔍 {{with .Scrub}}Проверка целостности {{.FinishedAt.Format "02.01.2006 15:04"}}: проверено {{.Checked}},
+                    {{if .Corrupt}}<span style="color:#dc3545;">повреждено {{.Corrupt}}</span>{{else}}повреждений нет{{end}}
+                    {{if .Error}}<span style="color:#dc3545;">({{.Error}})</span>{{end}}{{else}}Проверка целостности ещё не выполнялась{{end}}
+                    · <a href="#" class="download-link" onclick="return runScrub();">Проверить сейчас</a></p>
             </div>
 
+            <form class="filters" method="get" action="/">
+                {{if .SelectedProjectID}}<input type="hidden" name="project_id" value="{{.SelectedProjectID}}">{{end}}
+                <input type="hidden" name="sort" value="{{.List.Sort}}">
+                <input type="hidden" name="order" value="{{.List.Order}}">
+                <select name="group" class="project-select">
+                    <option value="">Все группы</option>
+                    {{range .List.Groups}}
+                    <option value="{{.}}" {{if eq $.List.Group .}}selected{{end}}>{{.}}</option>
+                    {{end}}
+                </select>
+                <input type="search" name="q" value="{{.List.Search}}" placeholder="Имя файла" class="filter-input">
+                <label>с <input type="date" name="since" value="{{.List.Since}}" class="filter-input"></label>
+                <label>по <input type="date" name="until" value="{{.List.Until}}" class="filter-input"></label>
+                <button type="submit" class="btn btn-secondary">Найти</button>
+                {{if .List.Filtered}}<a href="/{{if .SelectedProjectID}}?project_id={{.SelectedProjectID}}{{end}}" class="download-link">Сбросить</a>{{end}}
+            </form>
+
             {{if .Files}}
             <table class="exports-table">
                 <thead>
                     <tr>
-                        <th>Файл</th>
-                        <th>Размер</th>
-                        <th>Дата создания</th>
+                        <th><input type="checkbox" id="selectAll" title="Выбрать все"></th>
+                        <th><a href="{{index .List.SortURLs "name"}}" class="sort-link">Файл {{index .List.SortMarks "name"}}</a></th>
+                        <th>Группа</th>
+                        <th><a href="{{index .List.SortURLs "size"}}" class="sort-link">Размер {{index .List.SortMarks "size"}}</a></th>
+                        <th><a href="{{index .List.SortURLs "date"}}" class="sort-link">Дата создания {{index .List.SortMarks "date"}}</a></th>
                         <th>Действия</th>
                     </tr>
                 </thead>
@@ -463,7 +1019,18 @@ const htmlTemplate = `
                     <!-- JS будет рендерить сюда -->
                 </tbody>
             </table>
-            <button id="showMoreBtn" class="btn" style="display:none;">Показать ещё</button>
+            <div class="pager">
+                {{if .List.PrevURL}}<a href="{{.List.PrevURL}}" class="download-link">← Назад</a>{{end}}
+                <span>Страница {{.List.Page}} из {{.List.Pages}} · найдено файлов: {{.List.Total}}</span>
+                {{if .List.NextURL}}<a href="{{.List.NextURL}}" class="download-link">Вперёд →</a>{{end}}
+            </div>
+            <button id="zipBtn" class="btn btn-secondary" disabled>Скачать выбранные (ZIP)</button>
+            <button id="deleteBtn" class="btn btn-danger" disabled>Удалить выбранные</button>
+            {{else if .List.Filtered}}
+            <div class="empty-state">
+                <h3>Нет файлов по выбранным условиям</h3>
+                <p>Измените фильтры или сбросьте их.</p>
+            </div>
             {{else}}
             <div class="empty-state">
                 <h3>Экспорты не найдены</h3>
@@ -484,12 +1051,10 @@ const htmlTemplate = `
             headers: {'Content-Type': 'application/json'},
             body: JSON.stringify({ project_id: projectId })
         })
-            .then(r => r.json())
+            .then(r => r.ok ? r.json() : r.text().then(t => { throw new Error(t); }))
             .then(data => {
-                document.getElementById('exportStatus').style.display = 'block';
-                document.getElementById('exportStatus').textContent = 'Экспорт запущен! Обновите страницу через минуту.';
                 btn.textContent = 'Запустить экспорт сейчас';
-                btn.disabled = false;
+                watchRun(data.run_id, btn);
             })
             .catch(() => {
                 document.getElementById('exportStatus').style.display = 'block';
@@ -500,32 +1065,478 @@ const htmlTemplate = `
             });
     };
 
+// Только файлы текущей страницы: отбор, сортировка и страницы — на сервере
 const allFiles = {{ toJson .Files }};
-let shown = 10;
+const selected = new Set();
+
+// el создает элемент с текстом. Имена файлов и прочие данные хранилища не проверяются,
+// поэтому попадают на страницу только как текст и атрибуты, без разбора HTML
+function el(tag, text, className) {
+    const node = document.createElement(tag);
+    if (text) node.textContent = text;
+    if (className) node.className = className;
+    return node;
+}
+
+// link создает ссылку действия; onclick вызывается вместо перехода по ссылке
+function link(text, href, onclick) {
+    const a = el('a', text, 'download-link');
+    a.href = href;
+    if (onclick) {
+        a.addEventListener('click', e => { e.preventDefault(); onclick(); });
+    }
+    return a;
+}
 
 function renderFiles() {
     const tbody = document.getElementById('exportsTbody');
-    tbody.innerHTML = '';
-    for (let i = 0; i < Math.min(shown, allFiles.length); i++) {
-        const f = allFiles[i];
-        tbody.innerHTML += '<tr>' +
-            '<td>' + f.Name + '</td>' +
-            '<td>' + f.FormattedSize + '</td>' +
-            '<td>' + f.FormattedDate + '</td>' +
-            '<td><a href="/download/' + f.Name + '" class="download-link">Скачать</a></td>' +
-        '</tr>';
+    tbody.replaceChildren();
+    for (const f of allFiles) {
+        const tr = document.createElement('tr');
+
+        const check = el('input', '', 'file-check');
+        check.type = 'checkbox';
+        check.value = f.Name;
+        check.checked = selected.has(f.Name);
+        check.addEventListener('change', function() {
+            if (this.checked) selected.add(f.Name); else selected.delete(f.Name);
+            updateZipBtn();
+        });
+        tr.appendChild(el('td')).appendChild(check);
+
+        const name = tr.appendChild(el('td', f.Name));
+        if (f.Bundle) name.appendChild(el('span', 'ZIP', 'badge'));
+        if (f.Manifest) name.appendChild(el('span', 'MANIFEST', 'badge'));
+        if (f.Pinned) name.appendChild(el('span', '📌', 'badge badge-pin')).title = 'Закреплен: не удаляется автоочисткой';
+        if (f.Quarantined) name.appendChild(el('span', 'поврежден', 'badge badge-corrupt')).title = f.Problem;
+
+        const group = tr.appendChild(el('td', f.GroupName));
+        if (f.RunID) {
+            group.appendChild(el('br'));
+            group.appendChild(el('small', f.RunID)).title = 'ID запуска';
+        }
+
+        const size = tr.appendChild(el('td', f.FormattedSize));
+        if (f.Rows) {
+            size.appendChild(el('br'));
+            size.appendChild(el('small', f.Rows + ' строк'));
+        }
+
+        tr.appendChild(el('td', f.FormattedDate));
+
+        const path = encodeURIComponent(f.Name);
+        const actions = [link('Скачать', '/download/' + path)];
+        if (!f.Bundle && !f.Manifest) actions.push(link('Просмотр', '/preview/' + path));
+        if (f.Compression) actions.push(link(f.Compression, '/download/' + path + '?raw=1'));
+        if (f.Manifest) {
+            const verify = link('Проверить', '/verify/' + path);
+            verify.target = '_blank';
+            actions.push(verify);
+        }
+        const pinAction = f.Pinned ? 'unpin' : 'pin';
+        actions.push(link(f.Pinned ? 'Открепить' : 'Закрепить', '#', () => pin(pinAction, {filename: f.Name})));
+        if (f.RunID) {
+            actions.push(link((f.Pinned ? 'открепить' : 'закрепить') + ' запуск', '#', () => pin(pinAction, {run_id: f.RunID})));
+        }
+        if (!f.Pinned) {
+            const del = link('Удалить', '#', () => deleteFiles([f.Name]));
+            del.classList.add('delete-link');
+            actions.push(del);
+        }
+        const cell = tr.appendChild(el('td'));
+        actions.forEach((a, i) => {
+            if (i > 0) cell.append(' · ');
+            cell.appendChild(a);
+        });
+
+        tbody.appendChild(tr);
     }
-    document.getElementById('showMoreBtn').style.display = shown < allFiles.length ? '' : 'none';
 }
 
-document.getElementById('showMoreBtn').onclick = function() {
-    shown += 10;
+function runScrub() {
+    fetch('/scrub', { method: 'POST' })
+        .then(r => r.ok ? r.json() : r.text().then(t => { throw new Error(t); }))
+        .then(() => alert('Проверка целостности запущена. Обновите страницу через несколько минут.'))
+        .catch(e => alert('Не удалось запустить проверку: ' + e.message));
+    return false;
+}
+
+function pin(action, target) {
+    fetch('/' + action, {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(target)
+    })
+        .then(r => r.ok ? location.reload() : r.text().then(t => { throw new Error(t); }))
+        .catch(e => alert('Не удалось изменить закрепление: ' + e.message));
+    return false;
+}
+
+document.getElementById('snapshotBtn').onclick = function() {
+    const label = prompt(selected.size > 0
+        ? 'Метка снимка (выбрано файлов: ' + selected.size + ')'
+        : 'Метка снимка (в снимок попадет последний экспорт каждой группы)');
+    if (!label) return;
+    fetch('/snapshots', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({ label: label, files: Array.from(selected) })
+    })
+        .then(r => r.ok ? r.json() : r.text().then(t => { throw new Error(t); }))
+        .then(() => { window.location = '/snapshots'; })
+        .catch(e => alert('Не удалось создать снимок: ' + e.message));
+};
+
+function updateZipBtn() {
+    const btn = document.getElementById('zipBtn');
+    btn.disabled = selected.size === 0;
+    btn.textContent = selected.size > 0 ? 'Скачать выбранные (ZIP, ' + selected.size + ')' : 'Скачать выбранные (ZIP)';
+    const del = document.getElementById('deleteBtn');
+    del.disabled = selected.size === 0;
+    del.textContent = selected.size > 0 ? 'Удалить выбранные (' + selected.size + ')' : 'Удалить выбранные';
+}
+
+function deleteFiles(files) {
+    const question = files.length === 1
+        ? 'Удалить файл ' + files[0] + '?'
+        : 'Удалить выбранные файлы (' + files.length + ')?';
+    if (!confirm(question + ' Восстановить их будет нельзя.')) return false;
+    fetch('/delete', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({ files: files })
+    })
+        .then(r => r.ok ? r.json() : r.text().then(t => { throw new Error(t); }))
+        .then(data => {
+            if (data.failed.length > 0) {
+                alert('Не удалось удалить:\n' + data.failed.map(f => f.name + ': ' + f.error).join('\n'));
+            }
+            location.reload();
+        })
+        .catch(e => alert('Не удалось удалить: ' + e.message));
+    return false;
+}
+
+document.getElementById('selectAll').onchange = function() {
+    for (const f of allFiles) {
+        if (this.checked) selected.add(f.Name); else selected.delete(f.Name);
+    }
     renderFiles();
+    updateZipBtn();
+};
+
+document.getElementById('deleteBtn').onclick = function() {
+    deleteFiles(Array.from(selected));
+};
+
+document.getElementById('zipBtn').onclick = function() {
+    const params = new URLSearchParams();
+    selected.forEach(name => params.append('files', name));
+    window.location = '/download-zip?' + params.toString();
 };
 
 window.onload = function() {
     renderFiles();
-    if (allFiles.length > 10) document.getElementById('showMoreBtn').style.display = '';
+};
+    </script>
+</body>
+</html>
+`
+
+// HTML шаблон страницы снимков релизов
+const snapshotsTemplate = `
+<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Снимки релизов — TestOps Export Manager</title>
+    {{template "styles" .}}
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Снимки релизов</h1>
+            <p>Неизменяемые копии экспортов, не удаляются автоочисткой</p>
+        </div>
+
+        <div class="content">
+            <div class="actions">
+                <a href="/" class="btn btn-secondary">← К экспортам</a>
+            </div>
+
+            {{range .}}
+            <h3>📸 {{.Label}} <small style="color:#6c757d;">{{.FormattedDate}}, {{.FormattedSize}}</small>
+                · <a href="/snapshots/{{.Label}}.zip" class="download-link">Скачать ZIP</a></h3>
+            <table class="exports-table">
+                <thead>
+                    <tr>
+                        <th>Файл</th>
+                        <th>Проект</th>
+                        <th>Группа</th>
+                        <th>Размер</th>
+                        <th>Действия</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .Files}}
+                    <tr>
+                        <td>{{.Name}}</td>
+                        <td>{{.ProjectID}}</td>
+                        <td>{{.GroupName}}</td>
+                        <td>{{.FormattedSize}}</td>
+                        <td><a href="/download/{{.Name}}" class="download-link">Скачать</a>
+                            · <a href="/preview/{{.Name}}" class="download-link">Просмотр</a></td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+            {{else}}
+            <div class="empty-state">
+                <h3>Снимков пока нет</h3>
+                <p>Создайте снимок кнопкой «Создать снимок релиза» на главной странице.</p>
+            </div>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// HTML шаблон страницы просмотра CSV
+const previewTemplate = `
+<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Filename}} — TestOps Export Manager</title>
+    {{template "styles" .}}
+    <style>
+        .preview-wrap {
+            overflow-x: auto;
+        }
+        .preview-table td {
+            white-space: pre-wrap;
+            word-break: break-word;
+            vertical-align: top;
+            min-width: 120px;
+            max-width: 520px;
+        }
+        .column-list {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 6px 15px;
+            margin-bottom: 15px;
+            font-size: 14px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.Filename}}</h1>
+            <p>Строк в файле: {{.TotalRows}}</p>
+        </div>
+
+        <div class="content">
+            <div class="actions">
+                <a href="/" class="btn btn-secondary">← К экспортам</a>
+                <a href="/download/{{.Filename}}" class="btn btn-secondary">Скачать</a>
+            </div>
+
+            <form method="get" action="/preview/{{.Filename}}">
+                <div class="filters">
+                    <input type="search" name="q" value="{{.Search}}" placeholder="Поиск" class="filter-input">
+                    <select name="in" class="project-select">
+                        <option value="">Во всех колонках</option>
+                        {{range .Columns}}
+                        <option value="{{.Name}}" {{if eq $.SearchIn .Name}}selected{{end}}>{{.Name}}</option>
+                        {{end}}
+                    </select>
+                    <button type="submit" class="btn btn-secondary">Показать</button>
+                    {{if .Search}}<a href="/preview/{{.Filename}}" class="download-link">Сбросить</a>{{end}}
+                </div>
+                <div class="column-list">
+                    {{range .Columns}}
+                    <label><input type="checkbox" name="col" value="{{.Name}}" {{if .Selected}}checked{{end}}> {{.Name}}</label>
+                    {{end}}
+                </div>
+            </form>
+
+            {{if .Rows}}
+            <div class="preview-wrap">
+                <table class="exports-table preview-table">
+                    <thead>
+                        <tr>
+                            {{range .Header}}<th>{{.}}</th>{{end}}
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {{range .Rows}}
+                        <tr>
+                            {{range .}}<td>{{.}}</td>{{end}}
+                        </tr>
+                        {{end}}
+                    </tbody>
+                </table>
+            </div>
+            <div class="pager">
+                {{if .PrevURL}}<a href="{{.PrevURL}}" class="download-link">← Назад</a>{{end}}
+                <span>Страница {{.Page}} из {{.Pages}} · найдено строк: {{.Total}}</span>
+                {{if .NextURL}}<a href="{{.NextURL}}" class="download-link">Вперёд →</a>{{end}}
+            </div>
+            {{else}}
+            <div class="empty-state">
+                <h3>Строки не найдены</h3>
+                <p>{{if .Search}}Измените условие поиска.{{else}}Файл не содержит строк.{{end}}</p>
+            </div>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// HTML шаблон страницы групп экспорта
+const groupsTemplate = `
+<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Группы — TestOps Export Manager</title>
+    {{template "styles" .}}
+    <style>
+        .group-summary {
+            margin-bottom: 20px;
+            color: #495057;
+        }
+        .group-summary .warn {
+            color: #dc3545;
+            font-weight: 500;
+        }
+        .stale td {
+            background-color: #fff3cd;
+        }
+        span.stage-saved {
+            color: #28a745;
+        }
+        span.stage-failed, span.stage-retry, .run-error {
+            color: #dc3545;
+        }
+        .run-error {
+            word-break: break-word;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Группы экспорта</h1>
+            <p>Свежесть экспорта каждой группы и запуск экспорта отдельных групп</p>
+        </div>
+
+        <div class="content">
+            <div class="actions">
+                <a href="/{{if .SelectedProjectID}}?project_id={{.SelectedProjectID}}{{end}}" class="btn btn-secondary">← К экспортам</a>
+                <form id="projectForm" style="display:inline;">
+                    <label for="projectSelect" class="project-select-label">Проект:</label>
+                    <select id="projectSelect" name="project_id" class="project-select" onchange="document.getElementById('projectForm').submit()">
+                        <option value="" {{if eq .SelectedProjectID 0}}selected{{end}}>Все</option>
+                        {{range .Projects}}
+                        <option value="{{.ID}}" {{if eq $.SelectedProjectID .ID}}selected{{end}}>{{.Name}}</option>
+                        {{end}}
+                    </select>
+                </form>
+                <button id="exportSelectedBtn" class="btn" disabled>Экспортировать выбранные</button>
+            </div>
+
+            {{template "progress" .}}
+
+            {{if .Groups}}
+            <div class="group-summary">
+                Групп: {{len .Groups}}
+                {{if .StaleAfter}}· <span {{if .StaleCount}}class="warn"{{end}}>устаревших (старше {{.StaleAfter}} или без экспортов): {{.StaleCount}}</span>{{end}}
+                · <span {{if .FailedCount}}class="warn"{{end}}>последний запуск с ошибкой: {{.FailedCount}}</span>
+            </div>
+            <table class="exports-table">
+                <thead>
+                    <tr>
+                        <th></th>
+                        <th>Проект</th>
+                        <th>Группа</th>
+                        <th>Последний экспорт</th>
+                        <th>Возраст</th>
+                        <th>Размер</th>
+                        <th>Последний запуск</th>
+                        <th>Действия</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .Groups}}
+                    <tr{{if .Stale}} class="stale" title="Экспорт устарел"{{end}}>
+                        <td>{{if .CanTrigger}}<input type="checkbox" class="group-check" data-project="{{.ProjectID}}" value="{{.GroupID}}">{{end}}</td>
+                        <td>{{.ProjectID}}</td>
+                        <td>{{.GroupName}}<br><small title="ID группы">{{.GroupID}}</small></td>
+                        <td>{{with .LastFile}}{{.FormattedDate}}<br><small><a href="/download/{{.Name}}" class="download-link">{{.Name}}</a></small>{{else}}Нет{{end}}{{if .LastFile}}<br><small><a href="{{.LatestURL}}" class="download-link" title="Адрес не меняется и всегда ведет на последний экспорт группы">постоянная ссылка</a></small>{{end}}</td>
+                        <td>{{if .LastFile}}{{.Age}}{{else}}—{{end}}{{if .Stale}} ⚠️{{end}}</td>
+                        <td>{{with .LastFile}}{{.FormattedSize}}{{if .Rows}}<br><small>{{.Rows}} строк</small>{{end}}{{else}}—{{end}}</td>
+                        <td>{{if .LastRun}}<span class="stage-{{.LastRun.Stage}}" title="Запуск {{.LastRunID}}">{{stageLabel .LastRun.Stage}}</span>{{if .LastRun.Error}}<br><small class="run-error">{{.LastRun.Error}}</small>{{end}}{{else}}<small>нет запусков</small>{{end}}</td>
+                        <td>{{if .CanTrigger}}<button type="button" class="btn btn-secondary" onclick="exportGroups(this, {{.ProjectID}}, [{{.GroupID}}])">Экспортировать сейчас</button>{{end}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+            {{else}}
+            <div class="empty-state">
+                <h3>Группы не настроены</h3>
+                <p>Добавьте проекты и группы в файл проектов (PROJECTS_CONFIG).</p>
+            </div>
+            {{end}}
+        </div>
+    </div>
+    <script>
+// exportGroups запускает экспорт групп одного проекта и показывает его ход
+function exportGroups(btn, projectId, groupIds) {
+    btn.disabled = true;
+    fetch('/export', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({ project_id: projectId, group_ids: groupIds })
+    })
+        .then(r => r.ok ? r.json() : r.text().then(t => { throw new Error(t); }))
+        .then(data => {
+            document.querySelectorAll('.exports-table .btn, #exportSelectedBtn').forEach(b => b.disabled = true);
+            watchRun(data.run_id, btn);
+        })
+        .catch(e => {
+            alert('Не удалось запустить экспорт: ' + e.message);
+            btn.disabled = false;
+        });
+}
+
+function checkedGroups() {
+    return Array.from(document.querySelectorAll('.group-check:checked'));
+}
+
+document.querySelectorAll('.group-check').forEach(cb => {
+    cb.onchange = function() {
+        const count = checkedGroups().length;
+        const btn = document.getElementById('exportSelectedBtn');
+        btn.disabled = count === 0;
+        btn.textContent = count > 0 ? 'Экспортировать выбранные (' + count + ')' : 'Экспортировать выбранные';
+    };
+});
+
+document.getElementById('exportSelectedBtn').onclick = function() {
+    const checked = checkedGroups();
+    const projects = new Set(checked.map(cb => cb.dataset.project));
+    if (projects.size !== 1) {
+        alert('Выберите группы одного проекта');
+        return;
+    }
+    exportGroups(this, Number(checked[0].dataset.project), checked.map(cb => Number(cb.value)));
 };
     </script>
 </body>